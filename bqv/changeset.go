@@ -0,0 +1,120 @@
+package bqv
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+)
+
+// PendingViews returns the keys ("dataset.view") of the views which don't exist yet or whose query has changed.
+func (g *DependencyGraph) PendingViews(ctx context.Context, client *bigquery.Client) (map[string]bool, error) {
+	pending := make(map[string]bool)
+	for key, config := range g.configs {
+		m, err := config.getViewMetaDataIfExists(ctx, client)
+		if err != nil {
+			logrus.Errorf("Failed to get the metadata of view(%s): %s", key, err.Error())
+			return nil, err
+		}
+		if m == nil || config.queryChanged(m.ViewQuery, g.queries[key]) {
+			logrus.Debugf("View(%s) is pending", key)
+			pending[key] = true
+		}
+	}
+	return pending, nil
+}
+
+// DryRun tests the query of the view in dry-run mode in the same way as ViewConfig.DryRun,
+// except that the new queries of the pending upstream views are inlined into it.
+// It lets a batch of new views which depend on each other get validated before any of them is created.
+func (g *DependencyGraph) DryRun(ctx context.Context, client *bigquery.Client, v *ViewConfig, params map[string]string, pending map[string]bool) (bool, error) {
+	q, err := g.QueryWithInlinedViews(v, pending)
+	if err != nil {
+		logrus.Errorf("Failed to inline the pending views into view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+		return false, err
+	}
+	return v.dryRun(ctx, client, params, q)
+}
+
+// SortViews returns the views in the order of their dependencies, the upstream views first.
+// The views in a circular reference keep their order.
+func (g *DependencyGraph) SortViews(views []*ViewConfig) []*ViewConfig {
+	selected := make(map[string]*ViewConfig)
+	for _, v := range views {
+		selected[viewKey(v.DatasetName, v.ViewName)] = v
+	}
+	ret := make([]*ViewConfig, 0, len(views))
+	visited := make(map[string]bool)
+	var visit func(key string)
+	visit = func(key string) {
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		for _, upstream := range g.upstreams[key] {
+			visit(upstream)
+		}
+		if v, ok := selected[key]; ok {
+			ret = append(ret, v)
+		}
+	}
+	for _, v := range views {
+		visit(viewKey(v.DatasetName, v.ViewName))
+	}
+	return ret
+}
+
+// ApplyViews applies the views in the order of their dependencies so that a view is created or updated
+// after the managed views it selects from. diffs are the diffs of the views computed before any of them is applied.
+// A diff is reused only if no upstream view of the view has changed in this run, and the view is compared again otherwise
+// because the dry run of its new query depends on the new upstream views.
+// A view whose new query fails in dry run only because its upstream view failed to apply and is still pending
+// is reported as blocked by the upstream view instead of as a broken query.
+// It returns the number of the views which failed to apply.
+func (g *DependencyGraph) ApplyViews(ctx context.Context, client *bigquery.Client, views []*ViewConfig, params map[string]string, diffs map[*ViewConfig]*ViewDiff) int {
+	changed := make(map[string]bool)
+	failed := make(map[string]bool)
+	errCount := 0
+	for _, v := range g.SortViews(views) {
+		key := viewKey(v.DatasetName, v.ViewName)
+		diff, ok := diffs[v]
+		if !ok || g.upstreamIn(v, changed) != "" {
+			var err error
+			if diff, err = v.Diff(ctx, client, params); err != nil {
+				logrus.Errorf("Failed to create diff of view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+				failed[key] = true
+				errCount++
+				continue
+			}
+		}
+		if diff != nil && diff.DryRunError != nil {
+			if upstream := g.upstreamIn(v, failed); upstream != "" {
+				logrus.Errorf("Skipped view(%s) because its upstream view(%s) failed to apply: %s", key, upstream, diff.DryRunError.Error())
+				failed[key] = true
+				errCount++
+				continue
+			}
+		}
+		applied, err := v.ApplyDiff(ctx, client, params, diff)
+		if err != nil {
+			logrus.Errorf("Failed to create view %s.%s: %s", v.DatasetName, v.ViewName, err.Error())
+			failed[key] = true
+			errCount++
+			continue
+		}
+		if applied {
+			changed[key] = true
+		}
+	}
+	return errCount
+}
+
+// upstreamIn returns the first upstream view of the view in the keys, or "" if there's none.
+func (g *DependencyGraph) upstreamIn(v *ViewConfig, keys map[string]bool) string {
+	for _, upstream := range g.AllUpstreams(v) {
+		if key := viewKey(upstream.DatasetName, upstream.ViewName); keys[key] {
+			return key
+		}
+	}
+	return ""
+}
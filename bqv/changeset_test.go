@@ -0,0 +1,154 @@
+package bqv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/option"
+)
+
+// fakeBigQuery serves the views of the dataset "a" in the project "p". Like BigQuery, it refuses the dry runs
+// and the views which select from the tables of the dataset which don't exist.
+type fakeBigQuery struct {
+	mu    sync.Mutex
+	views map[string]string
+}
+
+func (f *fakeBigQuery) missingTable(q string) string {
+	refs, _ := TableRefs(q, "p")
+	for _, ref := range refs {
+		if _, ok := f.views[ref.TableID]; ref.DatasetID == "a" && !ok {
+			return ref.String()
+		}
+	}
+	return ""
+}
+
+func (f *fakeBigQuery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	notFound := func(name string) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error": {"code": 404, "message": "Not found: Table %s", "errors": [{"reason": "notFound", "message": "Not found: Table %s"}]}}`, name, name)
+	}
+	var body struct {
+		TableReference struct {
+			TableID string `json:"tableId"`
+		} `json:"tableReference"`
+		View struct {
+			Query string `json:"query"`
+		} `json:"view"`
+		Configuration struct {
+			Query struct {
+				Query string `json:"query"`
+			} `json:"query"`
+		} `json:"configuration"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	table := func(name string) {
+		fmt.Fprintf(w, `{"etag": "e", "type": "VIEW", "tableReference": {"projectId": "p", "datasetId": "a", "tableId": %q}, "view": {"query": %q}, "schema": {"fields": [{"name": "id", "type": "INTEGER"}]}}`, name, f.views[name])
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/projects/p/")
+	switch {
+	case path == "jobs":
+		if name := f.missingTable(body.Configuration.Query.Query); name != "" {
+			notFound(name)
+			return
+		}
+		fmt.Fprint(w, `{"jobReference": {"projectId": "p", "jobId": "j"}, "status": {"state": "DONE"}, "statistics": {"query": {"schema": {"fields": [{"name": "id", "type": "INTEGER"}]}}}}`)
+	case path == "datasets/a":
+		fmt.Fprint(w, `{"datasetReference": {"projectId": "p", "datasetId": "a"}}`)
+	case path == "datasets/a/tables" && r.Method == http.MethodPost:
+		if name := f.missingTable(body.View.Query); name != "" {
+			notFound(name)
+			return
+		}
+		f.views[body.TableReference.TableID] = body.View.Query
+		table(body.TableReference.TableID)
+	case strings.HasPrefix(path, "datasets/a/tables/"):
+		name := strings.TrimPrefix(path, "datasets/a/tables/")
+		if _, ok := f.views[name]; !ok {
+			notFound("p:a." + name)
+			return
+		}
+		table(name)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error": {"code": 400, "message": "unexpected request %s %s"}}`, r.Method, r.URL.Path)
+	}
+}
+
+func newFakeBigQueryClient(t *testing.T, f *fakeBigQuery) (*bigquery.Client, func()) {
+	server := httptest.NewServer(f)
+	client, err := NewClient(context.Background(), "p", option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		server.Close()
+		t.Fatalf("Failed to create a client: %s", err.Error())
+	}
+	return client, server.Close
+}
+
+func TestApplyViewsCreatesDependentViews(t *testing.T) {
+	f := &fakeBigQuery{views: map[string]string{}}
+	client, closeServer := newFakeBigQueryClient(t, f)
+	defer closeServer()
+	ctx := context.Background()
+
+	upstream := &ViewConfig{DatasetName: "a", ViewName: "x", Query: "SELECT 1 AS id"}
+	downstream := &ViewConfig{DatasetName: "a", ViewName: "y", Query: "SELECT id FROM a.x"}
+	views := []*ViewConfig{downstream, upstream}
+	g, err := NewDependencyGraph(views, nil, "p")
+	if err != nil {
+		t.Fatalf("Failed to build the graph: %s", err.Error())
+	}
+
+	// The diffs are computed before any view is applied as apply does for the downstream check.
+	diffs := make(map[*ViewConfig]*ViewDiff)
+	for _, v := range views {
+		if diffs[v], err = v.Diff(ctx, client, nil); err != nil {
+			t.Fatalf("Failed to create the diff: %s", err.Error())
+		}
+	}
+	if diffs[downstream].DryRunError == nil {
+		t.Fatal("The dry run of the downstream view should fail before its upstream view is created")
+	}
+
+	if errCount := g.ApplyViews(ctx, client, views, nil, diffs); errCount != 0 {
+		t.Errorf("Both views should be applied but %d failed", errCount)
+	}
+	if _, ok := f.views["y"]; !ok {
+		t.Errorf("The downstream view should be created: %v", f.views)
+	}
+}
+
+func TestApplyViewsSkipsViewsOfFailedUpstream(t *testing.T) {
+	f := &fakeBigQuery{views: map[string]string{}}
+	client, closeServer := newFakeBigQueryClient(t, f)
+	defer closeServer()
+	ctx := context.Background()
+
+	upstream := &ViewConfig{DatasetName: "a", ViewName: "x", Query: "SELECT id FROM a.dropped"}
+	downstream := &ViewConfig{DatasetName: "a", ViewName: "y", Query: "SELECT id FROM a.x"}
+	independent := &ViewConfig{DatasetName: "a", ViewName: "z", Query: "SELECT 1 AS id"}
+	views := []*ViewConfig{upstream, downstream, independent}
+	g, err := NewDependencyGraph(views, nil, "p")
+	if err != nil {
+		t.Fatalf("Failed to build the graph: %s", err.Error())
+	}
+
+	if errCount := g.ApplyViews(ctx, client, views, nil, nil); errCount != 2 {
+		t.Errorf("The broken view and its downstream view should fail but %d failed", errCount)
+	}
+	if _, ok := f.views["z"]; !ok || len(f.views) != 1 {
+		t.Errorf("Only the independent view should be created: %v", f.views)
+	}
+}
@@ -0,0 +1,276 @@
+package bqv
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// ContractColumn is an output column the view promises to produce, declared in the contract of meta.json.
+type ContractColumn struct {
+	Name string `json:"name"`
+	// Type is the BigQuery type of the column such as STRING, INT64, ARRAY<STRING> and STRUCT<id INT64, name STRING>.
+	Type string `json:"type"`
+	// Mode is NULLABLE, REQUIRED or REPEATED. NULLABLE is used if it's empty.
+	Mode string `json:"mode,omitempty"`
+}
+
+// ContractViolationType is the kind of a ContractViolation.
+type ContractViolationType string
+
+// The kinds of contract violations.
+const (
+	ColumnMissing     ContractViolationType = "missing column"
+	ColumnExtra       ContractViolationType = "extra column"
+	ColumnTypeChanged ContractViolationType = "type mismatch"
+	ColumnModeChanged ContractViolationType = "mode mismatch"
+)
+
+// ContractViolation is a difference between the contract and the schema the query produces.
+type ContractViolation struct {
+	Type     ContractViolationType
+	Column   string
+	Expected string
+	Actual   string
+}
+
+func (c ContractViolation) String() string {
+	switch c.Type {
+	case ColumnMissing:
+		return fmt.Sprintf("%s: %s %s", c.Type, c.Column, c.Expected)
+	case ColumnExtra:
+		return fmt.Sprintf("%s: %s %s", c.Type, c.Column, c.Actual)
+	}
+	return fmt.Sprintf("%s: %s: expected %s but got %s", c.Type, c.Column, c.Expected, c.Actual)
+}
+
+// ContractError is the error returned when the query of the view breaks its contract.
+type ContractError struct {
+	DatasetName string
+	ViewName    string
+	Violations  []ContractViolation
+}
+
+func (e *ContractError) Error() string {
+	s := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		s = append(s, violation.String())
+	}
+	return fmt.Sprintf("view(%s.%s) breaks its contract: %s", e.DatasetName, e.ViewName, strings.Join(s, ", "))
+}
+
+// CheckContract compares the schema with the contract and returns the violations.
+// The types such as ARRAY<STRING> and STRUCT<id INT64, tags ARRAY<STRING>> are compared with the REPEATED and RECORD fields,
+// and the fields of the structs are compared as the columns named like payload.id.
+// It returns an empty slice if the contract is empty.
+func CheckContract(schema bigquery.Schema, contract []ContractColumn) []ContractViolation {
+	violations := make([]ContractViolation, 0)
+	if len(contract) == 0 {
+		return violations
+	}
+	return checkContractColumns(violations, "", schema, contract)
+}
+
+// checkContractColumns appends the violations of the fields against the columns, whose names are prefixed with prefix.
+func checkContractColumns(violations []ContractViolation, prefix string, schema bigquery.Schema, contract []ContractColumn) []ContractViolation {
+	fields := make(map[string]*bigquery.FieldSchema)
+	for _, field := range schema {
+		fields[strings.ToLower(field.Name)] = field
+	}
+	declared := make(map[string]bool)
+	for _, column := range contract {
+		declared[strings.ToLower(column.Name)] = true
+		name := prefix + column.Name
+		expectedType, repeated, nested, ok := parseContractType(column.Type)
+		if !ok {
+			expectedType = normalizeFieldType(column.Type)
+		}
+		expectedMode := normalizeFieldMode(column.Mode)
+		if repeated {
+			expectedMode = "REPEATED"
+		}
+
+		field, ok := fields[strings.ToLower(column.Name)]
+		if !ok {
+			violations = append(violations, ContractViolation{Type: ColumnMissing, Column: name, Expected: expectedType + " " + expectedMode})
+			continue
+		}
+		actualType := string(field.Type)
+		if actualType != expectedType {
+			violations = append(violations, ContractViolation{Type: ColumnTypeChanged, Column: name, Expected: expectedType, Actual: actualType})
+		}
+		if actualMode := fieldMode(field); actualMode != expectedMode {
+			violations = append(violations, ContractViolation{Type: ColumnModeChanged, Column: name, Expected: expectedMode, Actual: actualMode})
+		}
+		// A STRUCT without its fields such as "STRUCT" leaves the fields unchecked.
+		if actualType == expectedType && nested != nil {
+			violations = checkContractColumns(violations, name+".", field.Schema, nested)
+		}
+	}
+	for _, field := range schema {
+		if !declared[strings.ToLower(field.Name)] {
+			violations = append(violations, ContractViolation{Type: ColumnExtra, Column: prefix + field.Name, Actual: string(field.Type) + " " + fieldMode(field)})
+		}
+	}
+	return violations
+}
+
+// parseContractType parses a type of Standard SQL such as ARRAY<STRUCT<id INT64, tags ARRAY<STRING>>>.
+// It returns the type the BigQuery API returns, whether it's an array and the fields if it's a STRUCT with the fields.
+// The parameters of the types such as NUMERIC(10, 2) are ignored because the schema doesn't have them.
+func parseContractType(t string) (string, bool, []ContractColumn, bool) {
+	p := &contractTypeParser{tokens: lexContractType(t)}
+	fieldType, repeated, fields, ok := p.parseType()
+	if !ok || p.i != len(p.tokens) {
+		return "", false, nil, false
+	}
+	return fieldType, repeated, fields, true
+}
+
+type contractTypeParser struct {
+	tokens []string
+	i      int
+}
+
+func (p *contractTypeParser) next() string {
+	if p.i >= len(p.tokens) {
+		return ""
+	}
+	p.i++
+	return p.tokens[p.i-1]
+}
+
+func (p *contractTypeParser) peek() string {
+	if p.i >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.i]
+}
+
+func (p *contractTypeParser) parseType() (string, bool, []ContractColumn, bool) {
+	switch word := strings.ToUpper(p.next()); word {
+	case "ARRAY":
+		if p.next() != "<" {
+			return "", false, nil, false
+		}
+		fieldType, repeated, fields, ok := p.parseType()
+		// BigQuery has no array of arrays.
+		if !ok || repeated || p.next() != ">" {
+			return "", false, nil, false
+		}
+		return fieldType, true, fields, true
+	case "STRUCT", "RECORD":
+		if p.peek() != "<" {
+			return string(bigquery.RecordFieldType), false, nil, true
+		}
+		p.next()
+		fields := make([]ContractColumn, 0)
+		if p.peek() == ">" {
+			p.next()
+			return string(bigquery.RecordFieldType), false, fields, true
+		}
+		for {
+			name := p.next()
+			if name == "" || strings.ContainsAny(name, "<>,") {
+				return "", false, nil, false
+			}
+			start := p.i
+			if _, _, _, ok := p.parseType(); !ok {
+				return "", false, nil, false
+			}
+			fields = append(fields, ContractColumn{Name: strings.Trim(name, "`"), Type: strings.Join(p.tokens[start:p.i], " ")})
+			switch p.next() {
+			case ">":
+				return string(bigquery.RecordFieldType), false, fields, true
+			case ",":
+			default:
+				return "", false, nil, false
+			}
+		}
+	case "", "<", ">", ",":
+		return "", false, nil, false
+	default:
+		return normalizeFieldType(word), false, nil, true
+	}
+}
+
+// lexContractType splits the type into the names, the quoted names and the punctuations.
+// The parameters in the parentheses are dropped.
+func lexContractType(t string) []string {
+	tokens := make([]string, 0)
+	for i := 0; i < len(t); {
+		c := t[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			for depth := 0; i < len(t); i++ {
+				if t[i] == '(' {
+					depth++
+				} else if t[i] == ')' {
+					if depth--; depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+		case c == '`':
+			end := strings.IndexByte(t[i+1:], '`')
+			if end < 0 {
+				return append(tokens, t[i:])
+			}
+			tokens = append(tokens, t[i:i+2+end])
+			i += end + 2
+		case isWordByte(c):
+			start := i
+			for i < len(t) && isWordByte(t[i]) {
+				i++
+			}
+			tokens = append(tokens, t[start:i])
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// normalizeFieldType converts the type names of Standard SQL to the ones the BigQuery API returns.
+func normalizeFieldType(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	switch t {
+	case "INT64":
+		return string(bigquery.IntegerFieldType)
+	case "FLOAT64":
+		return string(bigquery.FloatFieldType)
+	case "BOOL":
+		return string(bigquery.BooleanFieldType)
+	case "STRUCT":
+		return string(bigquery.RecordFieldType)
+	case "DECIMAL":
+		return string(bigquery.NumericFieldType)
+	case "BIGDECIMAL":
+		// The client library we depend on has no constant for BIGNUMERIC.
+		return "BIGNUMERIC"
+	}
+	return t
+}
+
+func normalizeFieldMode(mode string) string {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	if mode == "" {
+		return "NULLABLE"
+	}
+	return mode
+}
+
+func fieldMode(field *bigquery.FieldSchema) string {
+	switch {
+	case field.Repeated:
+		return "REPEATED"
+	case field.Required:
+		return "REQUIRED"
+	}
+	return "NULLABLE"
+}
@@ -0,0 +1,115 @@
+package bqv
+
+import (
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestCheckContract(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "amount", Type: bigquery.StringFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType},
+		{Name: "debug", Type: bigquery.BooleanFieldType},
+	}
+	contract := []ContractColumn{
+		{Name: "id", Type: "INT64", Mode: "required"},
+		{Name: "amount", Type: "NUMERIC"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "created_at", Type: "TIMESTAMP"},
+	}
+
+	violations := CheckContract(schema, contract)
+	expected := []ContractViolation{
+		{Type: ColumnTypeChanged, Column: "amount", Expected: "NUMERIC", Actual: "STRING"},
+		{Type: ColumnModeChanged, Column: "tags", Expected: "REPEATED", Actual: "NULLABLE"},
+		{Type: ColumnMissing, Column: "created_at", Expected: "TIMESTAMP NULLABLE"},
+		{Type: ColumnExtra, Column: "debug", Actual: "BOOLEAN NULLABLE"},
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("%d violations were expected but got %v", len(expected), violations)
+	}
+	for i := range expected {
+		if violations[i] != expected[i] {
+			t.Errorf("%v was expected but got %v", expected[i], violations[i])
+		}
+	}
+}
+
+func TestCheckContractWithArraysAndStructs(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "ids", Type: bigquery.IntegerFieldType, Repeated: true},
+		{Name: "payload", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "amount", Type: bigquery.NumericFieldType},
+			{Name: "items", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+				{Name: "sku", Type: bigquery.StringFieldType},
+			}},
+			{Name: "debug", Type: bigquery.BooleanFieldType},
+		}},
+		{Name: "opaque", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "x", Type: bigquery.StringFieldType}}},
+	}
+	contract := []ContractColumn{
+		{Name: "tags", Type: "ARRAY<STRING>"},
+		{Name: "ids", Type: "array<string>"},
+		{Name: "payload", Type: "STRUCT<amount NUMERIC(10, 2), items ARRAY<STRUCT<sku STRING, `qty` INT64>>>"},
+		{Name: "opaque", Type: "STRUCT"},
+	}
+
+	violations := CheckContract(schema, contract)
+	expected := []ContractViolation{
+		{Type: ColumnTypeChanged, Column: "ids", Expected: "STRING", Actual: "INTEGER"},
+		{Type: ColumnMissing, Column: "payload.items.qty", Expected: "INTEGER NULLABLE"},
+		{Type: ColumnExtra, Column: "payload.debug", Actual: "BOOLEAN NULLABLE"},
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("%d violations were expected but got %v", len(expected), violations)
+	}
+	for i := range expected {
+		if violations[i] != expected[i] {
+			t.Errorf("%v was expected but got %v", expected[i], violations[i])
+		}
+	}
+}
+
+func TestParseContractType(t *testing.T) {
+	cases := map[string]string{
+		"ARRAY<STRING>":            "STRING REPEATED -1",
+		"STRUCT<a INT64, b BOOL>":  "RECORD NULLABLE 2",
+		"ARRAY<STRUCT<>>":          "RECORD REPEATED 0",
+		"STRUCT":                   "RECORD NULLABLE -1",
+		"DECIMAL(10, 2)":           "NUMERIC NULLABLE -1",
+		"ARRAY<BIGDECIMAL>":        "BIGNUMERIC REPEATED -1",
+		"ARRAY<ARRAY<STRING>>":     "invalid",
+		"STRUCT<a INT64":           "invalid",
+		"ARRAY<STRING> extra":      "invalid",
+		"STRUCT<`a b` STRING(10)>": "RECORD NULLABLE 1",
+	}
+	for typ, expected := range cases {
+		fieldType, repeated, fields, ok := parseContractType(typ)
+		actual := "invalid"
+		if ok {
+			mode := "NULLABLE"
+			if repeated {
+				mode = "REPEATED"
+			}
+			n := -1
+			if fields != nil {
+				n = len(fields)
+			}
+			actual = fmt.Sprintf("%s %s %d", fieldType, mode, n)
+		}
+		if actual != expected {
+			t.Errorf("%s: %s was expected but got %s", typ, expected, actual)
+		}
+	}
+}
+
+func TestCheckContractWithoutContract(t *testing.T) {
+	schema := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}
+	if violations := CheckContract(schema, nil); len(violations) != 0 {
+		t.Errorf("No violation was expected but got %v", violations)
+	}
+}
@@ -0,0 +1,121 @@
+package bqv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+)
+
+// CostEstimate is the estimated cost of a full SELECT * on a view, reported by a dry run.
+type CostEstimate struct {
+	BytesProcessed int64
+	// OldBytesProcessed is the estimate for the actual view. It's -1 if the view doesn't exist yet.
+	OldBytesProcessed int64
+	// ReferencedTables are the tables the query reads.
+	ReferencedTables []TableRef
+}
+
+// GrowthPercent returns how much the cost grows from the actual view in percent.
+// It returns false if it can't be calculated.
+func (c *CostEstimate) GrowthPercent() (float64, bool) {
+	if c.OldBytesProcessed <= 0 {
+		return 0, false
+	}
+	return float64(c.BytesProcessed-c.OldBytesProcessed) * 100 / float64(c.OldBytesProcessed), true
+}
+
+func (c *CostEstimate) String() string {
+	s := "estimated bytes processed: " + FormatBytes(c.BytesProcessed)
+	if growth, ok := c.GrowthPercent(); ok {
+		s += fmt.Sprintf(" (was %s, %+.1f%%)", FormatBytes(c.OldBytesProcessed), growth)
+	}
+	return s
+}
+
+// CostError is the error returned when a view would become more expensive than the limits.
+type CostError struct {
+	DatasetName string
+	ViewName    string
+	Reason      string
+}
+
+func (e *CostError) Error() string {
+	return fmt.Sprintf("view(%s.%s) would be too expensive: %s", e.DatasetName, e.ViewName, e.Reason)
+}
+
+// CheckCost returns a CostError if the cost exceeds the limits in the options.
+func (v *ViewConfig) CheckCost(cost *CostEstimate) error {
+	if cost == nil {
+		return nil
+	}
+	if limit := v.Options.MaxBytesProcessed; limit > 0 && cost.BytesProcessed > limit {
+		return &CostError{
+			DatasetName: v.DatasetName,
+			ViewName:    v.ViewName,
+			Reason:      fmt.Sprintf("%s is more than the limit %s", FormatBytes(cost.BytesProcessed), FormatBytes(limit)),
+		}
+	}
+	growth, ok := cost.GrowthPercent()
+	if limit := v.Options.MaxCostGrowthPercent; limit > 0 && ok && growth > limit {
+		return &CostError{
+			DatasetName: v.DatasetName,
+			ViewName:    v.ViewName,
+			Reason:      fmt.Sprintf("it grows by %.1f%% which is more than the limit %.1f%%", growth, limit),
+		}
+	}
+	return nil
+}
+
+// estimateCost makes a CostEstimate from the statistics of the dry run of the new query
+// and a dry run of a full SELECT * on the actual view if it exists.
+// OldBytesProcessed stays -1 if the dry run on the actual view fails.
+func (v *ViewConfig) estimateCost(ctx context.Context, client *bigquery.Client, stats *bigquery.QueryStatistics, exists bool) *CostEstimate {
+	cost := &CostEstimate{
+		BytesProcessed:    stats.TotalBytesProcessed,
+		OldBytesProcessed: -1,
+		ReferencedTables:  make([]TableRef, 0, len(stats.ReferencedTables)),
+	}
+	for _, t := range stats.ReferencedTables {
+		cost.ReferencedTables = append(cost.ReferencedTables, TableRef{ProjectID: t.ProjectID, DatasetID: t.DatasetID, TableID: t.TableID})
+	}
+	if !exists {
+		return cost
+	}
+
+	oldStats, err := runDryRun(ctx, client, fmt.Sprintf("SELECT * FROM `%s.%s`", v.DatasetName, v.ViewName))
+	if err != nil {
+		// The actual view is broken when its upstream table or column has been dropped,
+		// and the new query must still be applicable to repair it. Only the growth can't be checked then.
+		logrus.Warnf("Failed to estimate the cost of the actual view(%s.%s), so its growth won't be checked: %s", v.DatasetName, v.ViewName, err.Error())
+		return cost
+	}
+	cost.OldBytesProcessed = oldStats.TotalBytesProcessed
+	return cost
+}
+
+// FormatBytes returns the number of bytes in a human-readable format such as "1.5 GiB".
+func FormatBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f %s", f, units[i])
+}
+
+// formatTableRefs returns the references joined with commas.
+func formatTableRefs(refs []TableRef) string {
+	s := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		s = append(s, ref.String())
+	}
+	return strings.Join(s, ", ")
+}
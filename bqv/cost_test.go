@@ -0,0 +1,78 @@
+package bqv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/option"
+)
+
+func TestCheckCost(t *testing.T) {
+	v := &ViewConfig{DatasetName: "test", ViewName: "test"}
+	cost := &CostEstimate{BytesProcessed: 3 << 30, OldBytesProcessed: 2 << 30}
+
+	if err := v.CheckCost(cost); err != nil {
+		t.Errorf("No limit should be applied by default: %s", err.Error())
+	}
+
+	v.Options.MaxBytesProcessed = 1 << 30
+	if err := v.CheckCost(cost); err == nil {
+		t.Error("The cost should exceed the limit of bytes")
+	}
+
+	v.Options.MaxBytesProcessed = 0
+	v.Options.MaxCostGrowthPercent = 30
+	if err := v.CheckCost(cost); err == nil {
+		t.Error("The cost should exceed the limit of growth")
+	}
+
+	cost.OldBytesProcessed = -1
+	if err := v.CheckCost(cost); err != nil {
+		t.Errorf("The growth of a new view shouldn't be checked: %s", err.Error())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1536:          "1.5 KiB",
+		5 * (1 << 30): "5.0 GiB",
+	}
+	for n, expected := range cases {
+		if actual := FormatBytes(n); actual != expected {
+			t.Errorf("%s was expected but got %s", expected, actual)
+		}
+	}
+}
+
+func TestEstimateCostOfBrokenView(t *testing.T) {
+	// The fake BigQuery rejects the dry run on the actual view as it does when its upstream table has been dropped.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"code": 404, "message": "Not found: Table p:raw.dropped", "errors": [{"reason": "notFound", "message": "Not found: Table p:raw.dropped"}]}}`)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "p", option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create a client: %s", err.Error())
+	}
+
+	v := &ViewConfig{DatasetName: "test", ViewName: "broken", Options: Options{MaxCostGrowthPercent: 10, MaxBytesProcessed: 1 << 30}}
+	cost := v.estimateCost(ctx, client, &bigquery.QueryStatistics{TotalBytesProcessed: 2 << 30}, true)
+	if cost.OldBytesProcessed != -1 {
+		t.Errorf("The old cost should be unknown but got %d", cost.OldBytesProcessed)
+	}
+	if _, ok := cost.GrowthPercent(); ok {
+		t.Error("The growth shouldn't be calculated")
+	}
+	if err := v.CheckCost(cost); err == nil {
+		t.Error("The limit of bytes should still be checked")
+	}
+}
@@ -0,0 +1,58 @@
+package bqv
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// DatasetConfig is the metadata of a dataset declared in dataset.json in the dataset directory.
+// bqv uses it when it creates the dataset. Unlike meta.json, dataset.json isn't a template.
+type DatasetConfig struct {
+	FriendlyName string            `json:"friendlyName,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Location     string            `json:"location,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// readDatasetConfig reads dataset.json in dir of the tree. It returns nil if dir has no dataset.json.
+func readDatasetConfig(tree viewTree, dir string) (*DatasetConfig, error) {
+	name := path.Join(dir, "dataset.json")
+	data, err := fs.ReadFile(tree.fsys, name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &FileError{Path: tree.path(name), Err: err}
+	}
+	if errs := ValidateDatasetJSON(data); len(errs) > 0 {
+		loadErr := new(LoadError)
+		loadErr.add(tree.path(name), errs)
+		return nil, loadErr
+	}
+	ret := new(DatasetConfig)
+	if err = json.Unmarshal(data, ret); err != nil {
+		return nil, &FileError{Path: tree.path(name), Err: err}
+	}
+	return ret, nil
+}
+
+// datasetMetadata returns the metadata to create the dataset with. d can be nil.
+func (d *DatasetConfig) datasetMetadata(datasetName string) *bigquery.DatasetMetadata {
+	if d == nil {
+		return &bigquery.DatasetMetadata{Name: datasetName}
+	}
+	name := d.FriendlyName
+	if name == "" {
+		name = datasetName
+	}
+	return &bigquery.DatasetMetadata{
+		Name:        name,
+		Description: d.Description,
+		Location:    d.Location,
+		Labels:      d.Labels,
+	}
+}
@@ -0,0 +1,158 @@
+package bqv
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TableRef is a reference to a table or a view in a query.
+type TableRef struct {
+	// ProjectID is empty if the query doesn't specify the project.
+	ProjectID string
+	DatasetID string
+	TableID   string
+}
+
+func (r TableRef) String() string {
+	if r.ProjectID == "" {
+		return r.DatasetID + "." + r.TableID
+	}
+	return r.ProjectID + "." + r.DatasetID + "." + r.TableID
+}
+
+// DependencyGraph is the graph of the views which select from each other.
+type DependencyGraph struct {
+	projectID   string
+	configs     map[string]*ViewConfig
+	queries     map[string]string
+	upstreams   map[string][]string
+	downstreams map[string][]string
+	external    map[string][]TableRef
+	// existing caches whether the external tables exist, which UnknownReferences asks BigQuery.
+	existing map[string]bool
+}
+
+// NewDependencyGraph renders the queries of the views with the params and builds the graph of their references.
+// A reference to another project than projectID isn't regarded as a reference to a managed view.
+// Every project matches if projectID is empty.
+func NewDependencyGraph(configs []*ViewConfig, params map[string]string, projectID string) (*DependencyGraph, error) {
+	g := &DependencyGraph{
+		projectID:   projectID,
+		configs:     make(map[string]*ViewConfig),
+		queries:     make(map[string]string),
+		upstreams:   make(map[string][]string),
+		downstreams: make(map[string][]string),
+		external:    make(map[string][]TableRef),
+		existing:    make(map[string]bool),
+	}
+	for _, config := range configs {
+		q, err := config.QueryWithParam(params)
+		if err != nil {
+			return nil, err
+		}
+		key := viewKey(config.DatasetName, config.ViewName)
+		g.configs[key] = config
+		g.queries[key] = q
+	}
+	for key, q := range g.queries {
+		matches, err := findTableRefs(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the query of view(%s): %s", key, err.Error())
+		}
+		seen := make(map[string]bool)
+		for _, match := range matches {
+			upstream, ok := g.managedKey(match.Ref)
+			if !ok {
+				if !seen[match.Ref.String()] {
+					g.external[key] = append(g.external[key], match.Ref)
+				}
+				seen[match.Ref.String()] = true
+				continue
+			}
+			if seen[upstream] {
+				continue
+			}
+			seen[upstream] = true
+			g.upstreams[key] = append(g.upstreams[key], upstream)
+			g.downstreams[upstream] = append(g.downstreams[upstream], key)
+		}
+	}
+	g.sortEdges()
+	return g, nil
+}
+
+func (g *DependencyGraph) sortEdges() {
+	for key := range g.downstreams {
+		sort.Strings(g.downstreams[key])
+	}
+	for key := range g.upstreams {
+		sort.Strings(g.upstreams[key])
+	}
+}
+
+// Upstreams returns the managed views the view selects from directly.
+func (g *DependencyGraph) Upstreams(v *ViewConfig) []*ViewConfig {
+	return g.lookup(g.upstreams[viewKey(v.DatasetName, v.ViewName)])
+}
+
+// Downstreams returns the managed views which select from the view directly.
+func (g *DependencyGraph) Downstreams(v *ViewConfig) []*ViewConfig {
+	return g.lookup(g.downstreams[viewKey(v.DatasetName, v.ViewName)])
+}
+
+// AllDownstreams returns the managed views which select from the view directly or transitively.
+// The nearer views come first.
+func (g *DependencyGraph) AllDownstreams(v *ViewConfig) []*ViewConfig {
+	return g.lookup(g.walk(viewKey(v.DatasetName, v.ViewName), g.downstreams))
+}
+
+// AllUpstreams returns the managed views the view selects from directly or transitively.
+// The nearer views come first.
+func (g *DependencyGraph) AllUpstreams(v *ViewConfig) []*ViewConfig {
+	return g.lookup(g.walk(viewKey(v.DatasetName, v.ViewName), g.upstreams))
+}
+
+// ExternalReferences returns the tables and the views which the view selects from but bqv doesn't manage.
+func (g *DependencyGraph) ExternalReferences(v *ViewConfig) []TableRef {
+	return g.external[viewKey(v.DatasetName, v.ViewName)]
+}
+
+func (g *DependencyGraph) walk(start string, edges map[string][]string) []string {
+	ret := make([]string, 0)
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[key] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			ret = append(ret, next)
+			queue = append(queue, next)
+		}
+	}
+	return ret
+}
+
+func (g *DependencyGraph) lookup(keys []string) []*ViewConfig {
+	ret := make([]*ViewConfig, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, g.configs[key])
+	}
+	return ret
+}
+
+func (g *DependencyGraph) managedKey(ref TableRef) (string, bool) {
+	if ref.ProjectID != "" && g.projectID != "" && ref.ProjectID != g.projectID {
+		return "", false
+	}
+	key := viewKey(ref.DatasetID, ref.TableID)
+	_, ok := g.configs[key]
+	return key, ok
+}
+
+func viewKey(datasetName, viewName string) string {
+	return datasetName + "." + viewName
+}
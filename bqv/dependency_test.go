@@ -0,0 +1,77 @@
+package bqv
+
+import (
+	"strings"
+	"testing"
+)
+
+func testGraph(t *testing.T) (*DependencyGraph, map[string]*ViewConfig) {
+	configs := map[string]*ViewConfig{
+		"sales.orders":  {DatasetName: "sales", ViewName: "orders", Query: "SELECT * FROM `my-project.raw.orders`"},
+		"sales.daily":   {DatasetName: "sales", ViewName: "daily", Query: "SELECT day, SUM(amount) AS amount FROM sales.orders GROUP BY day"},
+		"sales.weekly":  {DatasetName: "sales", ViewName: "weekly", Query: "SELECT d.day FROM `sales.daily` AS d JOIN `other-project.sales.orders` o ON TRUE"},
+		"report.latest": {DatasetName: "report", ViewName: "latest", Query: "SELECT * FROM `my-project`.sales.weekly -- {{.env}}"},
+	}
+	list := make([]*ViewConfig, 0, len(configs))
+	for _, config := range configs {
+		list = append(list, config)
+	}
+	g, err := NewDependencyGraph(list, map[string]string{"env": "prod"}, "my-project")
+	if err != nil {
+		t.Fatalf("Failed to build the graph: %s", err.Error())
+	}
+	return g, configs
+}
+
+func viewNames(configs []*ViewConfig) string {
+	names := make([]string, 0, len(configs))
+	for _, config := range configs {
+		names = append(names, config.DatasetName+"."+config.ViewName)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestDependencyGraph(t *testing.T) {
+	g, configs := testGraph(t)
+
+	if names := viewNames(g.AllDownstreams(configs["sales.orders"])); names != "sales.daily,sales.weekly,report.latest" {
+		t.Errorf("Unexpected downstream views: %s", names)
+	}
+	if names := viewNames(g.Upstreams(configs["sales.weekly"])); names != "sales.daily" {
+		t.Errorf("Unexpected upstream views: %s", names)
+	}
+	external := g.ExternalReferences(configs["sales.weekly"])
+	if len(external) != 1 || external[0].String() != "other-project.sales.orders" {
+		t.Errorf("Unexpected external references: %v", external)
+	}
+}
+
+func TestQueryWithInlinedViews(t *testing.T) {
+	g, configs := testGraph(t)
+
+	q, err := g.QueryWithInlinedViews(configs["sales.weekly"], map[string]bool{"sales.daily": true})
+	if err != nil {
+		t.Fatalf("Failed to inline views: %s", err.Error())
+	}
+	expected := "SELECT d.day FROM (\nSELECT day, SUM(amount) AS amount FROM sales.orders GROUP BY day\n) AS d JOIN `other-project.sales.orders` o ON TRUE"
+	if q != expected {
+		t.Errorf("Unexpected query: %s", q)
+	}
+
+	q, err = g.QueryWithInlinedViews(configs["sales.daily"], map[string]bool{"sales.orders": true})
+	if err != nil {
+		t.Fatalf("Failed to inline views: %s", err.Error())
+	}
+	expected = "SELECT day, SUM(amount) AS amount FROM (\nSELECT * FROM `my-project.raw.orders`\n) AS `orders` GROUP BY day"
+	if q != expected {
+		t.Errorf("Unexpected query: %s", q)
+	}
+}
+
+func TestSortViews(t *testing.T) {
+	g, configs := testGraph(t)
+	views := []*ViewConfig{configs["report.latest"], configs["sales.orders"], configs["sales.weekly"]}
+	if names := viewNames(g.SortViews(views)); names != "sales.orders,sales.weekly,report.latest" {
+		t.Errorf("The upstream views should come first but got %s", names)
+	}
+}
@@ -0,0 +1,70 @@
+package bqv
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// DocIssueType is the kind of a DocIssue.
+type DocIssueType string
+
+// The kinds of documentation issues.
+const (
+	UnknownColumn      DocIssueType = "documented column not found"
+	UndocumentedColumn DocIssueType = "undocumented column"
+)
+
+// DocIssue is a mismatch between the columns documented in meta.json and the columns the view produces.
+type DocIssue struct {
+	Type   DocIssueType
+	Column string
+}
+
+func (d DocIssue) String() string {
+	return fmt.Sprintf("%s: %s", d.Type, d.Column)
+}
+
+// DocError is the error returned when the documentation of the view doesn't match its columns.
+type DocError struct {
+	DatasetName string
+	ViewName    string
+	Issues      []DocIssue
+}
+
+func (e *DocError) Error() string {
+	s := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		s = append(s, issue.String())
+	}
+	return fmt.Sprintf("documentation of view(%s.%s) doesn't match its columns: %s", e.DatasetName, e.ViewName, strings.Join(s, ", "))
+}
+
+// CheckColumnDocs compares the columns documented in meta.json with the schema of the view.
+// It reports the documented columns the view doesn't produce and the columns which aren't documented.
+// Undocumented columns aren't reported if no column is documented at all.
+func CheckColumnDocs(schema bigquery.Schema, columns []ColumnMetadata) []DocIssue {
+	issues := make([]DocIssue, 0)
+	if len(columns) == 0 {
+		return issues
+	}
+
+	fields := make(map[string]bool)
+	for _, field := range schema {
+		fields[field.Name] = true
+	}
+	documented := make(map[string]bool)
+	for _, column := range columns {
+		documented[column.Name] = true
+		if !fields[column.Name] {
+			issues = append(issues, DocIssue{Type: UnknownColumn, Column: column.Name})
+		}
+	}
+	for _, field := range schema {
+		if !documented[field.Name] {
+			issues = append(issues, DocIssue{Type: UndocumentedColumn, Column: field.Name})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,36 @@
+package bqv
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestCheckColumnDocs(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "customer_email", Type: bigquery.StringFieldType},
+	}
+	columns := []ColumnMetadata{
+		{Name: "id", Description: "identifier"},
+		{Name: "email", Description: "renamed to customer_email"},
+	}
+
+	issues := CheckColumnDocs(schema, columns)
+	expected := []DocIssue{
+		{Type: UnknownColumn, Column: "email"},
+		{Type: UndocumentedColumn, Column: "customer_email"},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("%d issues were expected but got %v", len(expected), issues)
+	}
+	for i := range expected {
+		if issues[i] != expected[i] {
+			t.Errorf("%v was expected but got %v", expected[i], issues[i])
+		}
+	}
+
+	if issues := CheckColumnDocs(schema, nil); len(issues) != 0 {
+		t.Errorf("No issue was expected for a view without documentation but got %v", issues)
+	}
+}
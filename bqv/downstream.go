@@ -0,0 +1,54 @@
+package bqv
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+)
+
+// DownstreamFailure is a downstream view which fails in dry run against the new query of its upstream view.
+type DownstreamFailure struct {
+	Upstream   *ViewConfig
+	Downstream *ViewConfig
+	Err        error
+}
+
+func (f DownstreamFailure) Error() string {
+	return fmt.Sprintf("view(%s.%s) would break with the new view(%s.%s): %s",
+		f.Downstream.DatasetName, f.Downstream.ViewName, f.Upstream.DatasetName, f.Upstream.ViewName, f.Err.Error())
+}
+
+// CheckDownstreams dry-runs the downstream views of the views which have breaking schema changes
+// against their new queries, and returns the downstream views which fail.
+// The new queries of the upstream views and the views between them are inlined into the downstream queries.
+func (g *DependencyGraph) CheckDownstreams(ctx context.Context, client *bigquery.Client, diffs []*ViewDiff) ([]DownstreamFailure, error) {
+	failures := make([]DownstreamFailure, 0)
+	for _, diff := range diffs {
+		if !diff.HasBreakingChanges() {
+			continue
+		}
+		upstream, ok := g.configs[viewKey(diff.DatasetName, diff.ViewName)]
+		if !ok {
+			continue
+		}
+
+		downstreams := g.AllDownstreams(upstream)
+		inlined := map[string]bool{viewKey(upstream.DatasetName, upstream.ViewName): true}
+		for _, downstream := range downstreams {
+			inlined[viewKey(downstream.DatasetName, downstream.ViewName)] = true
+		}
+		for _, downstream := range downstreams {
+			q, err := g.QueryWithInlinedViews(downstream, inlined)
+			if err != nil {
+				return nil, err
+			}
+			logrus.Debugf("Checking view(%s.%s) against the new view(%s.%s)", downstream.DatasetName, downstream.ViewName, upstream.DatasetName, upstream.ViewName)
+			if _, err := runDryRun(ctx, client, q); err != nil {
+				failures = append(failures, DownstreamFailure{Upstream: upstream, Downstream: downstream, Err: err})
+			}
+		}
+	}
+	return failures, nil
+}
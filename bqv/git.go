@@ -0,0 +1,171 @@
+package bqv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// GitChanges is the files changed in the working tree of a local git repository since the merge base of a ref and HEAD.
+type GitChanges struct {
+	root  string
+	base  string
+	files map[string]bool
+}
+
+// NewGitChanges finds the files changed since the merge base of ref and HEAD in the git repository dir belongs to.
+// The uncommitted changes and the untracked files are included.
+func NewGitChanges(dir, ref string) (*GitChanges, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	c := &GitChanges{root: strings.TrimSpace(string(root)), files: make(map[string]bool)}
+	if c.root, err = filepath.EvalSymlinks(c.root); err != nil {
+		return nil, err
+	}
+	base, err := git(dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	c.base = strings.TrimSpace(string(base))
+
+	changed, err := git(c.root, "diff", "--name-only", "--no-renames", "-z", c.base)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git(c.root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(string(changed)+string(untracked), "\x00") {
+		if name != "" {
+			c.files[filepath.Join(c.root, filepath.FromSlash(name))] = true
+		}
+	}
+	return c, nil
+}
+
+// Changed returns true if the file or any file in the directory at path has changed.
+func (c *GitChanges) Changed(path string) (bool, error) {
+	abs, err := c.abs(path)
+	if err != nil {
+		return false, err
+	}
+	for file := range c.files {
+		if file == abs || strings.HasPrefix(file, abs+string(filepath.Separator)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// OldFile returns the content of the file at path in the merge base. It returns false if the file didn't exist.
+func (c *GitChanges) OldFile(path string) ([]byte, bool, error) {
+	abs, err := c.abs(path)
+	if err != nil {
+		return nil, false, err
+	}
+	rel, err := filepath.Rel(c.root, abs)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := git(c.root, "cat-file", "-e", c.base+":"+filepath.ToSlash(rel)); err != nil {
+		return nil, false, nil
+	}
+	data, err := git(c.root, "show", c.base+":"+filepath.ToSlash(rel))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// abs returns the absolute path with the symbolic links in its existing part resolved,
+// so that it can be compared with the paths git reports.
+func (c *GitChanges) abs(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			return filepath.Join(resolved, rest), nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return filepath.Join(abs, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(abs), rest)
+		abs = parent
+	}
+}
+
+// ChangedViews returns the views whose files in baseDir have changed.
+// If the param file has changed, the views which render differently with the old params are returned too.
+func (c *GitChanges) ChangedViews(configs []*ViewConfig, baseDir, paramFile string, params map[string]string) ([]*ViewConfig, error) {
+	var oldParams map[string]string
+	paramsChanged, err := c.Changed(paramFile)
+	if err != nil {
+		return nil, err
+	}
+	if paramsChanged {
+		data, found, err := c.OldFile(paramFile)
+		if err != nil {
+			return nil, err
+		}
+		oldParams = make(map[string]string)
+		if found {
+			if err := json.Unmarshal(data, &oldParams); err != nil {
+				return nil, fmt.Errorf("invalid param file(%s) in %s: %s", paramFile, c.base, err.Error())
+			}
+		}
+	}
+
+	ret := make([]*ViewConfig, 0)
+	for _, config := range configs {
+		changed, err := c.Changed(filepath.Join(baseDir, config.DatasetName, config.ViewName))
+		if err != nil {
+			return nil, err
+		}
+		if changed || (paramsChanged && rendersDifferently(config, oldParams, params)) {
+			ret = append(ret, config)
+		}
+	}
+	return ret, nil
+}
+
+// rendersDifferently returns true if the query or the metadata of the view changes when it's rendered with other params.
+// A view which fails to render is regarded as changed.
+func rendersDifferently(v *ViewConfig, oldParams, newParams map[string]string) bool {
+	oldQuery, oldErr := v.QueryWithParam(oldParams)
+	newQuery, newErr := v.QueryWithParam(newParams)
+	if oldErr != nil || newErr != nil || oldQuery != newQuery {
+		return true
+	}
+	if len(v.MetadataSources) == 0 {
+		return false
+	}
+	oldMetadata, oldErr := renderMetadata(v.MetadataSources, oldParams)
+	newMetadata, newErr := renderMetadata(v.MetadataSources, newParams)
+	return oldErr != nil || newErr != nil || !reflect.DeepEqual(oldMetadata, newMetadata)
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %s: %s", strings.Join(args, " "), err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,167 @@
+package bqv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewGitFS returns the read-only filesystem of the directory dir at the git revision rev, such as a tag or a commit,
+// of the repository dir belongs to. Nothing gets checked out; the files are read from git when they're opened.
+func NewGitFS(dir, rev string) (fs.FS, error) {
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	treeish := rev + ":" + strings.TrimSuffix(strings.TrimSpace(string(prefix)), "/")
+	out, err := git(dir, "ls-tree", "-r", "-z", "--long", "--full-tree", treeish)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gitFS{dir: dir, files: make(map[string]gitBlob), dirs: map[string][]string{".": nil}}
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line == "" {
+			continue
+		}
+		// <mode> SP <type> SP <object> SP <size> TAB <file>
+		tab := strings.IndexByte(line, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(line[:tab])
+		if len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		var size int64
+		fmt.Sscan(fields[3], &size)
+		name := line[tab+1:]
+		g.files[name] = gitBlob{object: fields[2], size: size}
+		g.addToDir(name)
+	}
+	for dir := range g.dirs {
+		sort.Strings(g.dirs[dir])
+	}
+	return g, nil
+}
+
+// gitFS is a filesystem of a tree in a git repository.
+type gitFS struct {
+	dir   string
+	files map[string]gitBlob
+	// dirs are the names of the entries in each directory.
+	dirs map[string][]string
+}
+
+type gitBlob struct {
+	object string
+	size   int64
+}
+
+func (g *gitFS) addToDir(name string) {
+	for name != "." {
+		parent := path.Dir(name)
+		_, seen := g.dirs[parent]
+		g.dirs[parent] = append(g.dirs[parent], path.Base(name))
+		if seen {
+			return
+		}
+		name = parent
+	}
+}
+
+func (g *gitFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if blob, ok := g.files[name]; ok {
+		data, err := git(g.dir, "cat-file", "blob", blob.object)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &gitFile{info: gitFileInfo{name: path.Base(name), size: blob.size}, Reader: bytes.NewReader(data)}, nil
+	}
+	if _, ok := g.dirs[name]; ok {
+		return &gitDir{fs: g, name: name}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (g *gitFS) stat(name string) (fs.FileInfo, error) {
+	if blob, ok := g.files[name]; ok {
+		return gitFileInfo{name: path.Base(name), size: blob.size}, nil
+	}
+	if _, ok := g.dirs[name]; ok {
+		return gitFileInfo{name: path.Base(name), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat returns the FileInfo without reading the file from git.
+func (g *gitFS) Stat(name string) (fs.FileInfo, error) {
+	return g.stat(name)
+}
+
+type gitFile struct {
+	info gitFileInfo
+	*bytes.Reader
+}
+
+func (f *gitFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *gitFile) Close() error               { return nil }
+
+type gitDir struct {
+	fs     *gitFS
+	name   string
+	offset int
+}
+
+func (d *gitDir) Stat() (fs.FileInfo, error) { return d.fs.stat(d.name) }
+func (d *gitDir) Close() error               { return nil }
+
+func (d *gitDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *gitDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	names := d.fs.dirs[d.name][d.offset:]
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	if n > 0 && len(names) == 0 {
+		return nil, io.EOF
+	}
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, name := range names {
+		info, err := d.fs.stat(path.Join(d.name, name))
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	d.offset += len(names)
+	return entries, nil
+}
+
+type gitFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i gitFileInfo) Name() string { return i.name }
+func (i gitFileInfo) Size() int64  { return i.size }
+func (i gitFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+func (i gitFileInfo) ModTime() time.Time { return time.Time{} }
+func (i gitFileInfo) IsDir() bool        { return i.dir }
+func (i gitFileInfo) Sys() interface{}   { return nil }
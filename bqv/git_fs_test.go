@@ -0,0 +1,93 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitFS(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	baseDir := filepath.Join(dir, "views")
+	writeTestFile(t, filepath.Join(dir, "README.md"), "outside of the basedir")
+	writeTestFile(t, filepath.Join(baseDir, "sales", "daily", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(baseDir, "sales", "daily", "meta.json"), `{"description": "old"}`)
+	writeTestFile(t, filepath.Join(baseDir, "sales", "weekly", "query.sql"), "SELECT 7")
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	runGit(t, dir, "tag", "v1")
+
+	writeTestFile(t, filepath.Join(baseDir, "sales", "daily", "meta.json"), `{"description": "new"}`)
+	os.RemoveAll(filepath.Join(baseDir, "sales", "weekly"))
+	runGit(t, dir, "commit", "-q", "-am", "update")
+
+	fsys, err := NewGitFS(baseDir, "v1")
+	if err != nil {
+		t.Fatalf("Failed to open the tree: %s", err.Error())
+	}
+	if err := fstest.TestFS(fsys, "sales/daily/query.sql", "sales/daily/meta.json", "sales/weekly/query.sql"); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	configs, err := CreateViewConfigsFromFS(fsys, "v1:views")
+	if err != nil {
+		t.Fatalf("Failed to read views: %s", err.Error())
+	}
+	if names := viewNames(configs); names != "sales.daily,sales.weekly" {
+		t.Errorf("Unexpected views: %s", names)
+	}
+	if configs[0].MetadataFromFile.Description != "old" {
+		t.Errorf("the metadata at v1 should be read but got %v", configs[0].MetadataFromFile)
+	}
+	if configs[0].MetadataSources[0].Path != filepath.Join("v1:views", "sales", "daily", "meta.json") {
+		t.Errorf("Unexpected path: %s", configs[0].MetadataSources[0].Path)
+	}
+}
+
+func TestValidateAtRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "daily", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "daily", "meta.json"), `{"descripton": "typo"}`)
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	runGit(t, dir, "tag", "v1")
+
+	// The working tree is fixed but the view at v1 still has the typo.
+	writeTestFile(t, filepath.Join(dir, "sales", "daily", "meta.json"), `{"description": "fixed"}`)
+	if problems := Validate(dir, nil); len(problems) != 0 {
+		t.Errorf("the working tree should be valid but got %v", problems)
+	}
+
+	fsys, err := NewGitFS(dir, "v1")
+	if err != nil {
+		t.Fatalf("Failed to open the tree: %s", err.Error())
+	}
+	problems := ValidateFS(fsys, "v1:"+dir, nil)
+	if len(problems) != 1 || problems[0].Path != filepath.Join("v1:"+dir, "sales", "daily", "meta.json") {
+		t.Errorf("the typo at v1 should be reported but got %v", problems)
+	}
+	if problems := ValidateDependencies(fsys, "v1:"+dir, nil, MaxNestingDepth); len(problems) != 0 {
+		t.Errorf("no dependency problem was expected but got %v", problems)
+	}
+}
@@ -0,0 +1,60 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	if _, err := git(dir, args...); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+}
+
+func TestChangedViews(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	baseDir := filepath.Join(dir, "views")
+	paramFile := filepath.Join(dir, "params.json")
+	writeTestFile(t, filepath.Join(baseDir, "sales", "edited", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(baseDir, "sales", "env", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(baseDir, "sales", "env", "meta.json"), `{"labels": {"env": "{{.env}}"}}`)
+	writeTestFile(t, filepath.Join(baseDir, "sales", "region", "query.sql"), "SELECT '{{.region}}'")
+	writeTestFile(t, paramFile, `{"env": "dev", "region": "us"}`)
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	runGit(t, dir, "tag", "base")
+
+	writeTestFile(t, filepath.Join(baseDir, "sales", "edited", "query.sql"), "SELECT 2")
+	runGit(t, dir, "commit", "-q", "-am", "edit")
+	writeTestFile(t, paramFile, `{"env": "prod", "region": "us"}`)
+	writeTestFile(t, filepath.Join(baseDir, "sales", "new", "query.sql"), "SELECT 3")
+
+	configs, err := CreateViewConfigsFromDatasetDir(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to read views: %s", err.Error())
+	}
+	changes, err := NewGitChanges(baseDir, "base")
+	if err != nil {
+		t.Fatalf("Failed to find changes: %s", err.Error())
+	}
+	changed, err := changes.ChangedViews(configs, baseDir, paramFile, map[string]string{"env": "prod", "region": "us"})
+	if err != nil {
+		t.Fatalf("Failed to find changed views: %s", err.Error())
+	}
+	if names := viewNames(changed); names != "sales.edited,sales.env,sales.new" {
+		t.Errorf("Unexpected changed views: %s", names)
+	}
+}
@@ -0,0 +1,134 @@
+package bqv
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The types of the nodes of the exported dependency graph.
+const (
+	ManagedViewNode   = "view"
+	ExternalTableNode = "external"
+)
+
+// GraphNode is a managed view or a table or a view bqv doesn't manage in the exported dependency graph.
+type GraphNode struct {
+	// ID is "dataset.view" for a managed view and the reference in the query for an external one.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// GraphEdge means that To selects from From.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// GraphExport is the dependency graph to be written in DOT, Mermaid or JSON.
+type GraphExport struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Export returns the graph of the managed views and the external tables they select from.
+// If focus is given, only the focus view and its upstream views and/or downstream views are exported
+// with the external tables they select from.
+// Both of them are exported if neither upstream nor downstream is true.
+func (g *DependencyGraph) Export(focus *ViewConfig, upstream, downstream bool) *GraphExport {
+	views := make(map[string]bool)
+	if focus == nil {
+		for key := range g.configs {
+			views[key] = true
+		}
+	} else {
+		key := viewKey(focus.DatasetName, focus.ViewName)
+		views[key] = true
+		if upstream || !downstream {
+			for _, upstreamKey := range g.walk(key, g.upstreams) {
+				views[upstreamKey] = true
+			}
+		}
+		if downstream || !upstream {
+			for _, downstreamKey := range g.walk(key, g.downstreams) {
+				views[downstreamKey] = true
+			}
+		}
+	}
+
+	e := &GraphExport{Nodes: make([]GraphNode, 0), Edges: make([]GraphEdge, 0)}
+	external := make(map[string]bool)
+	for key := range views {
+		e.Nodes = append(e.Nodes, GraphNode{ID: key, Type: ManagedViewNode})
+		for _, upstreamKey := range g.upstreams[key] {
+			if views[upstreamKey] {
+				e.Edges = append(e.Edges, GraphEdge{From: upstreamKey, To: key})
+			}
+		}
+		for _, ref := range g.external[key] {
+			if !external[ref.String()] {
+				e.Nodes = append(e.Nodes, GraphNode{ID: ref.String(), Type: ExternalTableNode})
+			}
+			external[ref.String()] = true
+			e.Edges = append(e.Edges, GraphEdge{From: ref.String(), To: key})
+		}
+	}
+	sort.Slice(e.Nodes, func(i, j int) bool { return e.Nodes[i].ID < e.Nodes[j].ID })
+	sort.Slice(e.Edges, func(i, j int) bool {
+		if e.Edges[i].From != e.Edges[j].From {
+			return e.Edges[i].From < e.Edges[j].From
+		}
+		return e.Edges[i].To < e.Edges[j].To
+	})
+	return e
+}
+
+// DOT returns the graph in the Graphviz DOT language.
+func (e *GraphExport) DOT() string {
+	var buf strings.Builder
+	buf.WriteString("digraph bqv {\n  rankdir=LR;\n")
+	for _, node := range e.Nodes {
+		if node.Type == ExternalTableNode {
+			fmt.Fprintf(&buf, "  %s [shape=cylinder];\n", strconv.Quote(node.ID))
+		} else {
+			fmt.Fprintf(&buf, "  %s [shape=box];\n", strconv.Quote(node.ID))
+		}
+	}
+	for _, edge := range e.Edges {
+		fmt.Fprintf(&buf, "  %s -> %s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// Mermaid returns the graph in the Mermaid flowchart syntax.
+func (e *GraphExport) Mermaid() string {
+	var buf strings.Builder
+	buf.WriteString("graph LR\n")
+	// Mermaid doesn't allow dots in the node IDs, so the nodes are numbered.
+	ids := make(map[string]string)
+	for i, node := range e.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		label := strings.Replace(node.ID, `"`, "#quot;", -1)
+		if node.Type == ExternalTableNode {
+			fmt.Fprintf(&buf, "  %s[(\"%s\")]\n", ids[node.ID], label)
+		} else {
+			fmt.Fprintf(&buf, "  %s[\"%s\"]\n", ids[node.ID], label)
+		}
+	}
+	for _, edge := range e.Edges {
+		fmt.Fprintf(&buf, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+	return buf.String()
+}
+
+// JSON returns the graph in JSON.
+func (e *GraphExport) JSON() (string, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,49 @@
+package bqv
+
+import "testing"
+
+func TestExportDOT(t *testing.T) {
+	g, _ := testGraph(t)
+
+	expected := `digraph bqv {
+  rankdir=LR;
+  "my-project.raw.orders" [shape=cylinder];
+  "other-project.sales.orders" [shape=cylinder];
+  "report.latest" [shape=box];
+  "sales.daily" [shape=box];
+  "sales.orders" [shape=box];
+  "sales.weekly" [shape=box];
+  "my-project.raw.orders" -> "sales.orders";
+  "other-project.sales.orders" -> "sales.weekly";
+  "sales.daily" -> "sales.weekly";
+  "sales.orders" -> "sales.daily";
+  "sales.weekly" -> "report.latest";
+}
+`
+	if dot := g.Export(nil, false, false).DOT(); dot != expected {
+		t.Errorf("Unexpected DOT:\n%s", dot)
+	}
+}
+
+func TestExportFocus(t *testing.T) {
+	g, configs := testGraph(t)
+
+	expected := "graph LR\n" +
+		"  n0[(\"my-project.raw.orders\")]\n" +
+		"  n1[\"sales.daily\"]\n" +
+		"  n2[\"sales.orders\"]\n" +
+		"  n0 --> n2\n" +
+		"  n2 --> n1\n"
+	if mermaid := g.Export(configs["sales.daily"], true, false).Mermaid(); mermaid != expected {
+		t.Errorf("Unexpected Mermaid:\n%s", mermaid)
+	}
+
+	e := g.Export(configs["sales.weekly"], false, true)
+	if len(e.Nodes) != 3 || len(e.Edges) != 2 {
+		t.Errorf("Unexpected downstream graph: %v", e)
+	}
+	e = g.Export(configs["sales.daily"], false, false)
+	if len(e.Nodes) != 6 {
+		t.Errorf("Both the upstream and the downstream views should be exported but got %v", e.Nodes)
+	}
+}
@@ -0,0 +1,104 @@
+package bqv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Impact is a managed view which selects from a table directly or through other managed views.
+type Impact struct {
+	// View is the view in "dataset.view" format.
+	View string `json:"view"`
+	// Path is the shortest chain from the table to the view, such as ["raw.orders", "sales.orders", "sales.daily"].
+	Path []string `json:"path"`
+	// Direct is true if the view selects from the table directly.
+	Direct bool `json:"direct"`
+}
+
+// ParseTableRef parses the name of a table such as project.dataset.table and dataset.table.
+// Backticks around the name or its parts are ignored.
+func ParseTableRef(s string) (TableRef, error) {
+	parts := strings.Split(strings.Replace(s, "`", "", -1), ".")
+	for _, part := range parts {
+		if part == "" {
+			return TableRef{}, fmt.Errorf("invalid table name: %s", s)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return TableRef{DatasetID: parts[0], TableID: parts[1]}, nil
+	case 3:
+		return TableRef{ProjectID: parts[0], DatasetID: parts[1], TableID: parts[2]}, nil
+	}
+	return TableRef{}, fmt.Errorf("table name must be in (project).(dataset).(table) or (dataset).(table) format: %s", s)
+}
+
+// Impact returns the managed views which select from the table directly or transitively, the nearer ones first.
+// A reference without the project matches the table in the project of the graph.
+func (g *DependencyGraph) Impact(table TableRef) []Impact {
+	parents := make(map[string]string)
+	queue := make([]string, 0)
+	if key, ok := g.managedKey(table); ok {
+		// The table is a managed view itself.
+		for _, downstream := range g.downstreams[key] {
+			parents[downstream] = ""
+			queue = append(queue, downstream)
+		}
+	}
+	for _, key := range sortedKeys(g.external) {
+		if _, ok := parents[key]; ok {
+			continue
+		}
+		for _, ref := range g.external[key] {
+			if g.sameTable(ref, table) {
+				parents[key] = ""
+				queue = append(queue, key)
+				break
+			}
+		}
+	}
+
+	ret := make([]Impact, 0)
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		path := []string{key}
+		for parent := parents[key]; parent != ""; parent = parents[parent] {
+			path = append([]string{parent}, path...)
+		}
+		ret = append(ret, Impact{View: key, Path: append([]string{table.String()}, path...), Direct: parents[key] == ""})
+		for _, downstream := range g.downstreams[key] {
+			if _, ok := parents[downstream]; ok {
+				continue
+			}
+			parents[downstream] = key
+			queue = append(queue, downstream)
+		}
+	}
+	return ret
+}
+
+// sameTable returns true if the references point to the same table.
+func (g *DependencyGraph) sameTable(a, b TableRef) bool {
+	if a.DatasetID != b.DatasetID || a.TableID != b.TableID {
+		return false
+	}
+	projectA, projectB := a.ProjectID, b.ProjectID
+	if projectA == "" {
+		projectA = g.projectID
+	}
+	if projectB == "" {
+		projectB = g.projectID
+	}
+	return projectA == "" || projectB == "" || projectA == projectB
+}
+
+func sortedKeys(m map[string][]TableRef) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
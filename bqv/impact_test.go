@@ -0,0 +1,60 @@
+package bqv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImpact(t *testing.T) {
+	g, _ := testGraph(t)
+
+	cases := []struct {
+		table    string
+		expected []string
+	}{
+		{"my-project.raw.orders", []string{
+			"sales.orders: my-project.raw.orders -> sales.orders (direct)",
+			"sales.daily: my-project.raw.orders -> sales.orders -> sales.daily",
+			"sales.weekly: my-project.raw.orders -> sales.orders -> sales.daily -> sales.weekly",
+			"report.latest: my-project.raw.orders -> sales.orders -> sales.daily -> sales.weekly -> report.latest",
+		}},
+		{"raw.orders", []string{
+			"sales.orders: raw.orders -> sales.orders (direct)",
+			"sales.daily: raw.orders -> sales.orders -> sales.daily",
+			"sales.weekly: raw.orders -> sales.orders -> sales.daily -> sales.weekly",
+			"report.latest: raw.orders -> sales.orders -> sales.daily -> sales.weekly -> report.latest",
+		}},
+		{"other-project.raw.orders", []string{}},
+		{"`other-project`.sales.orders", []string{
+			"sales.weekly: other-project.sales.orders -> sales.weekly (direct)",
+			"report.latest: other-project.sales.orders -> sales.weekly -> report.latest",
+		}},
+		{"sales.weekly", []string{
+			"report.latest: sales.weekly -> report.latest (direct)",
+		}},
+	}
+	for _, c := range cases {
+		table, err := ParseTableRef(c.table)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %s", c.table, err.Error())
+		}
+		impacts := g.Impact(table)
+		actual := make([]string, 0, len(impacts))
+		for _, impact := range impacts {
+			s := impact.View + ": " + strings.Join(impact.Path, " -> ")
+			if impact.Direct {
+				s += " (direct)"
+			}
+			actual = append(actual, s)
+		}
+		if strings.Join(actual, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("Unexpected impact of %s:\n%s", c.table, strings.Join(actual, "\n"))
+		}
+	}
+
+	for _, s := range []string{"orders", "a.b.c.d", "a..b"} {
+		if _, err := ParseTableRef(s); err == nil {
+			t.Errorf("%s should be rejected", s)
+		}
+	}
+}
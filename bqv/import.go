@@ -0,0 +1,147 @@
+package bqv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+)
+
+// ImportedView is an existing view in BigQuery turned into the files bqv reads.
+type ImportedView struct {
+	DatasetName string
+	ViewName    string
+	Query       string
+	Metadata    Metadata
+}
+
+// ImportViews returns the views in the project which the selection matches.
+// The upstream and the downstream views of the selection aren't added. The views in legacy SQL are skipped because bqv creates views in standard SQL.
+func ImportViews(ctx context.Context, client *bigquery.Client, selection Selection) ([]*ImportedView, error) {
+	ret := make([]*ImportedView, 0)
+
+	dit := client.Datasets(ctx)
+	for {
+		ds, err := dit.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			logrus.Errorf("Failed to iterate datasets: %s", err.Error())
+			return nil, err
+		}
+
+		tit := ds.Tables(ctx)
+		for {
+			t, err := tit.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				logrus.Errorf("Failed to iterate tables: %s", err.Error())
+				return nil, err
+			}
+			if !selection.Matches(t.DatasetID, t.TableID) {
+				continue
+			}
+
+			m, err := t.Metadata(ctx)
+			if err != nil {
+				logrus.Errorf("Failed to get metadata of table(%s.%s): %s", t.DatasetID, t.TableID, err.Error())
+				return nil, err
+			}
+			if m.Type != bigquery.ViewTable {
+				continue
+			}
+			if m.UseLegacySQL {
+				logrus.Warnf("Skipping view(%s.%s) written in legacy SQL", t.DatasetID, t.TableID)
+				continue
+			}
+			tags, err := getColumnPolicyTags(ctx, client, t)
+			if err != nil {
+				logrus.Errorf("Failed to get policy tags of view(%s.%s): %s", t.DatasetID, t.TableID, err.Error())
+				return nil, err
+			}
+
+			ret = append(ret, &ImportedView{
+				DatasetName: t.DatasetID,
+				ViewName:    t.TableID,
+				Query:       m.ViewQuery,
+				Metadata:    importMetadata(m, t.TableID, tags),
+			})
+		}
+	}
+	return ret, nil
+}
+
+// importMetadata returns the Metadata which makes no change on the view.
+func importMetadata(m *bigquery.TableMetadata, viewName string, tags map[string][]string) Metadata {
+	md := Metadata{
+		Description: m.Description,
+		Schema:      []ColumnMetadata{},
+	}
+	if m.Name != viewName {
+		md.FriendlyName = m.Name
+	}
+	if !m.ExpirationTime.IsZero() {
+		md.ExpirationTime = m.ExpirationTime.UTC().Format(time.RFC3339)
+	}
+	if len(m.Labels) > 0 {
+		md.Labels = m.Labels
+	}
+
+	// Either all the columns or none of them are documented so that the column documentation check passes.
+	documented := len(tags) > 0
+	for _, field := range m.Schema {
+		if field.Description != "" {
+			documented = true
+		}
+	}
+	if documented {
+		for _, field := range m.Schema {
+			md.Schema = append(md.Schema, ColumnMetadata{Name: field.Name, Description: field.Description, PolicyTags: tags[field.Name]})
+		}
+	}
+	return md
+}
+
+// WriteImportedView writes query.sql and meta.json of the view into <dir>/<dataset>/<view>.
+// It returns false without writing anything if the directory of the view already exists and overwrite is false.
+func WriteImportedView(dir string, v *ImportedView, overwrite bool) (bool, error) {
+	viewDir := filepath.Join(dir, v.DatasetName, v.ViewName)
+	if _, err := os.Stat(viewDir); err == nil && !overwrite {
+		return false, nil
+	}
+	if err := os.MkdirAll(viewDir, 0755); err != nil {
+		return false, err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(viewDir, "query.sql"), []byte(escapeTemplate(v.Query)), 0644); err != nil {
+		return false, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(v.Metadata); err != nil {
+		return false, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(viewDir, "meta.json"), []byte(escapeTemplate(buf.String())), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// escapeTemplate escapes the text so that rendering it as a template gives the text back.
+func escapeTemplate(text string) string {
+	return strings.Replace(text, "{{", "{{`{{`}}", -1)
+}
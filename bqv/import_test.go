@@ -0,0 +1,84 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestImportMetadataMakesNoChange(t *testing.T) {
+	m := &bigquery.TableMetadata{
+		Description:    "sales",
+		Labels:         map[string]string{"team": "sales"},
+		ExpirationTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Schema: bigquery.Schema{
+			{Name: "id", Description: "identifier"},
+			{Name: "email"},
+		},
+	}
+	tags := map[string][]string{"email": {"projects/p/locations/us/taxonomies/1/policyTags/2"}}
+
+	md := importMetadata(m, "sales", tags)
+	if len(md.Schema) != 2 {
+		t.Errorf("all the columns should be documented but got %v", md.Schema)
+	}
+	changes, err := diffMetadata(m, &md, "sales", md.Labels, tags, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to compare metadata: %s", err.Error())
+	}
+	if len(changes) != 0 {
+		t.Errorf("the imported metadata shouldn't change the view but got %v", changes)
+	}
+	if issues := CheckColumnDocs(m.Schema, md.Schema); len(issues) != 0 {
+		t.Errorf("the imported metadata should pass the documentation check but got %v", issues)
+	}
+}
+
+func TestWriteImportedView(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	v := &ImportedView{
+		DatasetName: "sales",
+		ViewName:    "daily",
+		Query:       "SELECT REGEXP_EXTRACT(s, r'a{{2}}') AS s FROM t WHERE x < 1",
+		Metadata: Metadata{
+			FriendlyName: "Daily {{sales}}",
+			Description:  "a & b",
+			Schema:       []ColumnMetadata{},
+			Labels:       map[string]string{"team": "sales"},
+		},
+	}
+	if written, err := WriteImportedView(dir, v, false); err != nil || !written {
+		t.Fatalf("Failed to write the view: %v", err)
+	}
+	if written, _ := WriteImportedView(dir, v, false); written {
+		t.Error("the existing view shouldn't be overwritten")
+	}
+
+	configs, err := CreateViewConfigsFromDatasetDir(dir)
+	if err != nil || len(configs) != 1 {
+		t.Fatalf("Failed to read the imported view: %v", err)
+	}
+	q, err := configs[0].QueryWithParam(nil)
+	if err != nil {
+		t.Fatalf("Failed to render the query: %s", err.Error())
+	}
+	if q != v.Query {
+		t.Errorf("expected %q but got %q", v.Query, q)
+	}
+	md, err := configs[0].MetadataWithParam(nil)
+	if err != nil {
+		t.Fatalf("Failed to render the metadata: %s", err.Error())
+	}
+	if !reflect.DeepEqual(*md, v.Metadata) {
+		t.Errorf("expected %v but got %v", v.Metadata, *md)
+	}
+}
@@ -0,0 +1,49 @@
+package bqv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryWithInlinedViews returns the rendered query of the view whose references to the views in inlined
+// are replaced with their rendered queries as subqueries. The references in the inlined queries are replaced too.
+// inlined is keyed by "dataset.view".
+// It lets BigQuery dry-run the query against the new queries of the views which haven't been applied yet.
+func (g *DependencyGraph) QueryWithInlinedViews(v *ViewConfig, inlined map[string]bool) (string, error) {
+	return g.inline(viewKey(v.DatasetName, v.ViewName), inlined, map[string]bool{})
+}
+
+func (g *DependencyGraph) inline(key string, inlined, stack map[string]bool) (string, error) {
+	if stack[key] {
+		return "", fmt.Errorf("circular reference to view(%s)", key)
+	}
+	stack[key] = true
+	defer delete(stack, key)
+
+	q := g.queries[key]
+	var buf strings.Builder
+	last := 0
+	matches, err := findTableRefs(q)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the query of view(%s): %s", key, err.Error())
+	}
+	for _, match := range matches {
+		upstream, ok := g.managedKey(match.Ref)
+		if !ok || !inlined[upstream] {
+			continue
+		}
+		sub, err := g.inline(upstream, inlined, stack)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(q[last:match.Start])
+		// The newlines keep a trailing line comment in the subquery from commenting out the parenthesis.
+		buf.WriteString("(\n" + strings.TrimRight(strings.TrimSpace(sub), ";") + "\n)")
+		if !match.HasAlias {
+			buf.WriteString(" AS `" + match.Ref.TableID + "`")
+		}
+		last = match.End
+	}
+	buf.WriteString(q[last:])
+	return buf.String(), nil
+}
@@ -0,0 +1,337 @@
+package bqv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaError is a problem found in a JSON file with its position in the file.
+// Line and Column are zero if the position is unknown.
+type SchemaError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// SchemaErrors is the list of the problems found in a JSON file.
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	s := make([]string, 0, len(e))
+	for _, err := range e {
+		s = append(s, err.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+// jsonSchema is the subset of JSON Schema bqv uses to describe its files.
+type jsonSchema struct {
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+}
+
+// additional returns the schema of the properties not listed in Properties, and false if they aren't allowed.
+func (s *jsonSchema) additional() (*jsonSchema, bool) {
+	if len(s.AdditionalProperties) == 0 {
+		return nil, true
+	}
+	if string(s.AdditionalProperties) == "false" {
+		return nil, false
+	}
+	ret := new(jsonSchema)
+	if err := json.Unmarshal(s.AdditionalProperties, ret); err != nil {
+		return nil, true
+	}
+	return ret, true
+}
+
+var (
+	metadataSchema = mustParseSchema(MetadataSchema)
+	datasetSchema  = mustParseSchema(DatasetSchema)
+)
+
+func mustParseSchema(s string) *jsonSchema {
+	ret := new(jsonSchema)
+	if err := json.Unmarshal([]byte(s), ret); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// ValidateMetadataJSON validates the rendered meta.json against MetadataSchema.
+// It returns nil if no problem is found.
+func ValidateMetadataJSON(data []byte) SchemaErrors {
+	return validateJSON(data, metadataSchema, nil)
+}
+
+// ValidateDatasetJSON validates dataset.json against DatasetSchema.
+// It returns nil if no problem is found.
+func ValidateDatasetJSON(data []byte) SchemaErrors {
+	return validateJSON(data, datasetSchema, nil)
+}
+
+// positionFunc returns the 1-based line and column of an offset in the validated data.
+type positionFunc func(offset int64) (int, int)
+
+// validateJSON validates the data against the schema. pos maps the offsets in the data to the positions shown in the errors,
+// and the positions in the data are shown if it's nil.
+func validateJSON(data []byte, schema *jsonSchema, pos positionFunc) SchemaErrors {
+	if pos == nil {
+		pos = func(offset int64) (int, int) { return position(data, offset) }
+	}
+	v, err := parseJSON(data, pos)
+	if err != nil {
+		return SchemaErrors{*err}
+	}
+	c := &schemaChecker{pos: pos}
+	c.check(v, schema, "")
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+// validateMetadataValue validates the metadata decoded from a file other than JSON against MetadataSchema.
+// The problems don't have their positions.
+func validateMetadataValue(v interface{}) SchemaErrors {
+	c := new(schemaChecker)
+	c.check(valueOf(v), metadataSchema, "")
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+type schemaChecker struct {
+	// pos is nil if the values weren't read from JSON.
+	pos  positionFunc
+	errs SchemaErrors
+}
+
+func (c *schemaChecker) errorf(offset int64, format string, args ...interface{}) {
+	err := SchemaError{Message: fmt.Sprintf(format, args...)}
+	if offset >= 0 && c.pos != nil {
+		err.Line, err.Column = c.pos(offset)
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *schemaChecker) check(v *jsonValue, s *jsonSchema, path string) {
+	if s.Type != "" && v.Kind != s.Type {
+		c.errorf(v.Offset, "%s must be %s but got %s", describePath(path), s.Type, v.Kind)
+		return
+	}
+	switch v.Kind {
+	case "object":
+		additional, allowed := s.additional()
+		found := make(map[string]bool)
+		for _, member := range v.Members {
+			found[member.Name] = true
+			memberPath := member.Name
+			if path != "" {
+				memberPath = path + "." + member.Name
+			}
+			if p, ok := s.Properties[member.Name]; ok {
+				c.check(member.Value, p, memberPath)
+			} else if !allowed {
+				c.errorf(member.Offset, "unknown field %q%s", member.Name, inPath(path))
+			} else if additional != nil {
+				c.check(member.Value, additional, memberPath)
+			}
+		}
+		for _, name := range s.Required {
+			if !found[name] {
+				c.errorf(v.Offset, "missing required field %q%s", name, inPath(path))
+			}
+		}
+	case "array":
+		if s.Items == nil {
+			return
+		}
+		for i, item := range v.Items {
+			c.check(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the top-level value"
+	}
+	return path
+}
+
+func inPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + path
+}
+
+// position returns the 1-based line and column of the offset in data.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// jsonValue is a JSON value with the offset where it starts in the file.
+// Offset is -1 if the value wasn't read from JSON.
+type jsonValue struct {
+	Offset  int64
+	Kind    string
+	Members []jsonMember
+	Items   []*jsonValue
+}
+
+type jsonMember struct {
+	Name   string
+	Offset int64
+	Value  *jsonValue
+}
+
+// valueOf returns the jsonValue of the value decoded into interface{}.
+func valueOf(v interface{}) *jsonValue {
+	ret := &jsonValue{Offset: -1}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		ret.Kind = "object"
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ret.Members = append(ret.Members, jsonMember{Name: key, Offset: -1, Value: valueOf(v[key])})
+		}
+	case []interface{}:
+		ret.Kind = "array"
+		for _, item := range v {
+			ret.Items = append(ret.Items, valueOf(item))
+		}
+	case string:
+		ret.Kind = "string"
+	case bool:
+		ret.Kind = "boolean"
+	case nil:
+		ret.Kind = "null"
+	default:
+		ret.Kind = "number"
+	}
+	return ret
+}
+
+type jsonParser struct {
+	data []byte
+	dec  *json.Decoder
+	pos  positionFunc
+}
+
+func parseJSON(data []byte, pos positionFunc) (*jsonValue, *SchemaError) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	p := &jsonParser{data: data, dec: dec, pos: pos}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, offset, err := p.next(); err != io.EOF {
+		return nil, p.syntaxError(offset, "unexpected data after the top-level value")
+	}
+	return v, nil
+}
+
+// next returns the next token and the offset where it starts.
+func (p *jsonParser) next() (json.Token, int64, error) {
+	offset := p.dec.InputOffset()
+	for offset < int64(len(p.data)) && strings.IndexByte(" \t\r\n:,", p.data[offset]) >= 0 {
+		offset++
+	}
+	t, err := p.dec.Token()
+	return t, offset, err
+}
+
+func (p *jsonParser) value() (*jsonValue, *SchemaError) {
+	t, offset, err := p.next()
+	if err != nil {
+		return nil, p.tokenError(offset, err)
+	}
+	v := &jsonValue{Offset: offset}
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			v.Kind = "object"
+			for p.dec.More() {
+				key, keyOffset, err := p.next()
+				if err != nil {
+					return nil, p.tokenError(keyOffset, err)
+				}
+				member, valueErr := p.value()
+				if valueErr != nil {
+					return nil, valueErr
+				}
+				v.Members = append(v.Members, jsonMember{Name: key.(string), Offset: keyOffset, Value: member})
+			}
+		case '[':
+			v.Kind = "array"
+			for p.dec.More() {
+				item, err := p.value()
+				if err != nil {
+					return nil, err
+				}
+				v.Items = append(v.Items, item)
+			}
+		default:
+			return nil, p.syntaxError(offset, fmt.Sprintf("unexpected %q", t))
+		}
+		if _, end, err := p.next(); err != nil {
+			return nil, p.tokenError(end, err)
+		}
+	case string:
+		v.Kind = "string"
+	case json.Number:
+		v.Kind = "number"
+	case bool:
+		v.Kind = "boolean"
+	case nil:
+		v.Kind = "null"
+	}
+	return v, nil
+}
+
+func (p *jsonParser) tokenError(offset int64, err error) *SchemaError {
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		// Offset of json.SyntaxError points right after the invalid character.
+		return p.syntaxError(err.Offset-1, err.Error())
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return p.syntaxError(int64(len(p.data)), "unexpected end of JSON input")
+	}
+	return p.syntaxError(offset, err.Error())
+}
+
+func (p *jsonParser) syntaxError(offset int64, message string) *SchemaError {
+	line, column := p.pos(offset)
+	return &SchemaError{Line: line, Column: column, Message: message}
+}
@@ -0,0 +1,121 @@
+package bqv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateMetadataJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "valid",
+			data: `{
+  "description": "sales",
+  "schema": [{"name": "id", "policyTags": ["projects/p/locations/us/taxonomies/1/policyTags/2"]}],
+  "labels": {"env": "prod"},
+  "contract": [{"name": "id", "type": "INT64"}]
+}`,
+		},
+		{
+			name: "unknown field",
+			data: `{
+  "descripton": "sales"
+}`,
+			expected: []string{`2:3: unknown field "descripton"`},
+		},
+		{
+			name: "nested",
+			data: `{
+  "schema": [
+    {"name": "id"},
+    {"nmae": "amount", "attributes": {"sensitivity": 1}}
+  ]
+}`,
+			expected: []string{
+				`4:6: unknown field "nmae" in schema[1]`,
+				`4:54: schema[1].attributes.sensitivity must be string but got number`,
+				`4:5: missing required field "name" in schema[1]`,
+			},
+		},
+		{
+			name:     "syntax error",
+			data:     "{\n  \"description\": \"sales\",\n  \"labels\": {\"env\" \"prod\"}\n}",
+			expected: []string{"3:20: invalid character '\"' after object key"},
+		},
+		{
+			name:     "unexpected end",
+			data:     "{\n  \"description\": ",
+			expected: []string{"2:18: unexpected end of JSON input"},
+		},
+		{
+			name:     "not an object",
+			data:     `[]`,
+			expected: []string{"1:1: the top-level value must be object but got array"},
+		},
+	}
+
+	for _, c := range cases {
+		errs := ValidateMetadataJSON([]byte(c.data))
+		if len(errs) != len(c.expected) {
+			t.Errorf("%s: %d errors were expected but got %v", c.name, len(c.expected), errs)
+			continue
+		}
+		for i := range c.expected {
+			if errs[i].Error() != c.expected[i] {
+				t.Errorf("%s: expected %q but got %q", c.name, c.expected[i], errs[i].Error())
+			}
+		}
+	}
+}
+
+// TestMetadataSchemaCoversMetadata makes sure every field of meta.json bqv reads is in MetadataSchema.
+func TestMetadataSchemaCoversMetadata(t *testing.T) {
+	checkSchemaFields(t, reflect.TypeOf(Metadata{}), metadataSchema, "")
+}
+
+func checkSchemaFields(t *testing.T, typ reflect.Type, schema *jsonSchema, path string) {
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		p, ok := schema.Properties[name]
+		if !ok {
+			t.Errorf("field %s%s is missing in MetadataSchema", path, name)
+			continue
+		}
+		fieldType := typ.Field(i).Type
+		if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct {
+			checkSchemaFields(t, fieldType.Elem(), p.Items, path+name+"[].")
+		}
+	}
+	if len(schema.Properties) != typ.NumField() {
+		t.Errorf("MetadataSchema has %d fields in %s but bqv reads %d", len(schema.Properties), path, typ.NumField())
+	}
+}
+
+// TestDatasetSchemaCoversDatasetConfig makes sure every field of dataset.json bqv reads is in DatasetSchema.
+func TestDatasetSchemaCoversDatasetConfig(t *testing.T) {
+	checkSchemaFields(t, reflect.TypeOf(DatasetConfig{}), datasetSchema, "")
+	if errs := ValidateDatasetJSON([]byte("{\n  \"location\": \"US\",\n  \"lables\": {}\n}")); len(errs) != 1 || errs[0].Error() != `3:3: unknown field "lables"` {
+		t.Errorf("the unknown field should be reported but got %v", errs)
+	}
+}
+
+func TestMetadataPositionsInTemplate(t *testing.T) {
+	source := MetadataSource{Path: "meta.json", Format: MetadataJSON, Template: "{\n  \"description\": \"{{.env}}\", \"labls\": {},\n  {{if .x}}\"x\": 1{{end}}\"ttl\": 1\n}"}
+	_, err := source.render(map[string]string{"env": "a very long environment name"})
+	errs, ok := err.(SchemaErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("2 problems were expected but got %v", err)
+	}
+	// The columns are the ones in the template, not in the rendered text.
+	if errs[0].Error() != `2:30: unknown field "labls"` {
+		t.Errorf("unexpected problem: %s", errs[0])
+	}
+	if errs[1].Error() != `3:32: ttl must be string but got number` {
+		t.Errorf("unexpected problem: %s", errs[1])
+	}
+}
@@ -0,0 +1,72 @@
+package bqv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LabelMode decides which labels of the view bqv touches.
+type LabelMode string
+
+const (
+	// LabelModeReplace makes the labels of the view exactly the same as the ones in meta.json.
+	LabelModeReplace LabelMode = "replace"
+	// LabelModeOwned makes bqv touch only the label keys declared in meta.json or previously set by bqv,
+	// and leave the labels set by other systems alone.
+	LabelModeOwned LabelMode = "owned"
+)
+
+// ManagedLabelPrefix is the prefix of the marker labels which record the label keys set by bqv in LabelModeOwned.
+const ManagedLabelPrefix = "bqv-managed-"
+
+// maxLabelKeyLength is the maximum length of a label key BigQuery accepts.
+const maxLabelKeyLength = 63
+
+// ParseLabelMode returns the LabelMode named s.
+func ParseLabelMode(s string) (LabelMode, error) {
+	switch LabelMode(s) {
+	case "", LabelModeReplace:
+		return LabelModeReplace, nil
+	case LabelModeOwned:
+		return LabelModeOwned, nil
+	}
+	return "", fmt.Errorf("unknown label mode: %s", s)
+}
+
+// desiredLabels returns the labels the view should have after it gets applied,
+// and the keys of the labels set by other systems which are left alone.
+func desiredLabels(current, declared map[string]string, mode LabelMode) (map[string]string, []string, error) {
+	desired := make(map[string]string)
+	foreign := make([]string, 0)
+	if mode != LabelModeOwned {
+		for key, value := range declared {
+			desired[key] = value
+		}
+		return desired, foreign, nil
+	}
+
+	for key, value := range current {
+		if strings.HasPrefix(key, ManagedLabelPrefix) {
+			continue
+		}
+		if _, ok := current[ManagedLabelPrefix+key]; ok {
+			continue
+		}
+		if _, ok := declared[key]; ok {
+			continue
+		}
+		desired[key] = value
+		foreign = append(foreign, key)
+	}
+	for key, value := range declared {
+		marker := ManagedLabelPrefix + key
+		if len(marker) > maxLabelKeyLength {
+			return nil, nil, fmt.Errorf("label key(%s) is too long to be managed in %s mode", key, mode)
+		}
+		desired[key] = value
+		desired[marker] = ""
+	}
+	sort.Strings(foreign)
+	return desired, foreign, nil
+}
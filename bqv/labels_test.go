@@ -0,0 +1,54 @@
+package bqv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDesiredLabelsOwned(t *testing.T) {
+	current := map[string]string{
+		"billing":              "team-a",
+		"env":                  "dev",
+		"bqv-managed-env":      "",
+		"old":                  "value",
+		"bqv-managed-old":      "",
+		"cost_center":          "1234",
+		"bqv-managed-obsolete": "",
+	}
+	declared := map[string]string{"env": "prod", "team": "sales"}
+
+	labels, foreign, err := desiredLabels(current, declared, LabelModeOwned)
+	if err != nil {
+		t.Fatalf("Failed to decide labels: %s", err.Error())
+	}
+	expected := map[string]string{
+		"billing":          "team-a",
+		"cost_center":      "1234",
+		"env":              "prod",
+		"bqv-managed-env":  "",
+		"team":             "sales",
+		"bqv-managed-team": "",
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("%v was expected but got %v", expected, labels)
+	}
+	if !reflect.DeepEqual(foreign, []string{"billing", "cost_center"}) {
+		t.Errorf("Unexpected foreign labels: %v", foreign)
+	}
+}
+
+func TestDesiredLabelsReplace(t *testing.T) {
+	current := map[string]string{"billing": "team-a"}
+	declared := map[string]string{"env": "prod"}
+
+	labels, foreign, err := desiredLabels(current, declared, LabelModeReplace)
+	if err != nil {
+		t.Fatalf("Failed to decide labels: %s", err.Error())
+	}
+	if !reflect.DeepEqual(labels, declared) {
+		t.Errorf("%v was expected but got %v", declared, labels)
+	}
+	if len(foreign) != 0 {
+		t.Errorf("No foreign label was expected but got %v", foreign)
+	}
+}
@@ -0,0 +1,211 @@
+package bqv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+)
+
+// Lineage is the tables BigQuery reported as referenced in the dry run of the query of a view.
+type Lineage struct {
+	// QueryHash is the SHA-256 of the rendered query which was dry-run. The lineage is stale if the query changed.
+	QueryHash string `json:"query_hash"`
+	// ReferencedTables are the tables in the "project.dataset.table" format.
+	ReferencedTables []string `json:"referenced_tables"`
+}
+
+// LineageCache is the lineage of the views keyed by "dataset.view", which is kept in a JSON file between the runs.
+type LineageCache struct {
+	Views map[string]*Lineage `json:"views"`
+	// recorded has the keys of the views recorded since the cache was loaded.
+	recorded map[string]bool
+}
+
+// LoadLineageCache reads the cache from the file. It returns an empty cache if the file doesn't exist.
+func LoadLineageCache(path string) (*LineageCache, error) {
+	cache := &LineageCache{Views: make(map[string]*Lineage)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, cache); err != nil {
+		return nil, err
+	}
+	if cache.Views == nil {
+		cache.Views = make(map[string]*Lineage)
+	}
+	return cache, nil
+}
+
+// Save writes the cache to the file.
+func (c *LineageCache) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Record stores the tables BigQuery reported for the rendered query of the view.
+func (c *LineageCache) Record(v *ViewConfig, query string, tables []*bigquery.Table) {
+	refs := make([]string, 0, len(tables))
+	for _, t := range tables {
+		refs = append(refs, TableRef{ProjectID: t.ProjectID, DatasetID: t.DatasetID, TableID: t.TableID}.String())
+	}
+	sort.Strings(refs)
+	key := viewKey(v.DatasetName, v.ViewName)
+	c.Views[key] = &Lineage{QueryHash: queryHash(query), ReferencedTables: refs}
+	if c.recorded == nil {
+		c.recorded = make(map[string]bool)
+	}
+	c.recorded[key] = true
+}
+
+// Recorded returns the cache of only the views recorded since the cache was loaded.
+func (c *LineageCache) Recorded() *LineageCache {
+	ret := &LineageCache{Views: make(map[string]*Lineage)}
+	for key := range c.recorded {
+		ret.Views[key] = c.Views[key]
+	}
+	return ret
+}
+
+// Lookup returns the tables recorded for the view. It returns false if nothing is recorded for the rendered query.
+func (c *LineageCache) Lookup(v *ViewConfig, query string) ([]TableRef, bool) {
+	lineage, ok := c.Views[viewKey(v.DatasetName, v.ViewName)]
+	if !ok || lineage.QueryHash != queryHash(query) {
+		return nil, false
+	}
+	ret := make([]TableRef, 0, len(lineage.ReferencedTables))
+	for _, s := range lineage.ReferencedTables {
+		ref, err := ParseTableRef(s)
+		if err != nil {
+			logrus.Warnf("Ignoring the invalid table %q in the lineage of view(%s.%s)", s, v.DatasetName, v.ViewName)
+			continue
+		}
+		ret = append(ret, ref)
+	}
+	return ret, true
+}
+
+// recordLineage records the tables reported by the dry run of the rendered query of the view if Options.Lineage is set.
+func (v *ViewConfig) recordLineage(q string, stats *bigquery.QueryStatistics) {
+	if v.Options.Lineage != nil {
+		v.Options.Lineage.Record(v, q, stats.ReferencedTables)
+	}
+}
+
+// ensureLineage dry-runs the rendered query of the view only to record its lineage
+// if Options.Lineage is set and has nothing recorded for the query.
+func (v *ViewConfig) ensureLineage(ctx context.Context, client *bigquery.Client, q string) {
+	if v.Options.Lineage == nil {
+		return
+	}
+	if _, ok := v.Options.Lineage.Lookup(v, q); ok {
+		return
+	}
+	stats, err := runDryRun(ctx, client, q)
+	if err != nil {
+		logrus.Warnf("Failed to get the lineage of view(%s.%s) by dry run: %s", v.DatasetName, v.ViewName, err.Error())
+		return
+	}
+	v.recordLineage(q, stats)
+}
+
+func queryHash(q string) string {
+	sum := sha256.Sum256([]byte(q))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddLineage adds the tables cached for the current queries of the views to the references found in the queries.
+// The tables which the upstream managed views already reference are skipped
+// because BigQuery reports the tables behind the views as well.
+func (g *DependencyGraph) AddLineage(cache *LineageCache) {
+	for key, q := range g.queries {
+		refs, ok := cache.Lookup(g.configs[key], q)
+		if !ok {
+			continue
+		}
+		behind := make([]TableRef, 0)
+		for _, upstream := range g.walk(key, g.upstreams) {
+			behind = append(behind, g.external[upstream]...)
+		}
+		for _, ref := range refs {
+			if upstream, ok := g.managedKey(ref); ok {
+				if !contains(g.walk(key, g.upstreams), upstream) {
+					g.upstreams[key] = append(g.upstreams[key], upstream)
+					g.downstreams[upstream] = append(g.downstreams[upstream], key)
+				}
+				continue
+			}
+			if g.findTable(behind, ref) || g.findTable(g.external[key], ref) {
+				continue
+			}
+			g.external[key] = append(g.external[key], ref)
+		}
+	}
+	g.sortEdges()
+}
+
+func (g *DependencyGraph) findTable(refs []TableRef, ref TableRef) bool {
+	for _, r := range refs {
+		if g.sameTable(r, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// UnknownReferences returns the tables the view references which are neither managed views nor existing tables.
+// Both the references found in the query and the ones added by AddLineage are checked.
+func (g *DependencyGraph) UnknownReferences(ctx context.Context, client *bigquery.Client, v *ViewConfig) ([]TableRef, error) {
+	ret := make([]TableRef, 0)
+	for _, ref := range g.ExternalReferences(v) {
+		if ref.ProjectID == "" {
+			ref.ProjectID = g.projectID
+		}
+		if strings.HasSuffix(ref.TableID, "*") {
+			// Wildcard tables match the tables which exist when the view is queried.
+			continue
+		}
+		exists, ok := g.existing[ref.String()]
+		if !ok {
+			dataset := client.Dataset(ref.DatasetID)
+			if ref.ProjectID != "" {
+				dataset = client.DatasetInProject(ref.ProjectID, ref.DatasetID)
+			}
+			_, err := dataset.Table(ref.TableID).Metadata(ctx)
+			if err != nil && !hasStatusCode(err, http.StatusNotFound) {
+				logrus.Errorf("Failed to get the metadata of table(%s): %s", ref, err.Error())
+				return nil, err
+			}
+			exists = err == nil
+			g.existing[ref.String()] = exists
+		}
+		if !exists {
+			ret = append(ret, ref)
+		}
+	}
+	return ret, nil
+}
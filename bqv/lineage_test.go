@@ -0,0 +1,74 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestLineageCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lineage.json")
+
+	cache, err := LoadLineageCache(path)
+	if err != nil {
+		t.Fatalf("Failed to load the missing cache: %s", err.Error())
+	}
+	v := &ViewConfig{DatasetName: "sales", ViewName: "daily"}
+	cache.Record(v, "SELECT 1", []*bigquery.Table{{ProjectID: "p", DatasetID: "raw", TableID: "orders"}})
+	if err = cache.Save(path); err != nil {
+		t.Fatalf("Failed to save the cache: %s", err.Error())
+	}
+
+	cache, err = LoadLineageCache(path)
+	if err != nil {
+		t.Fatalf("Failed to load the cache: %s", err.Error())
+	}
+	refs, ok := cache.Lookup(v, "SELECT 1")
+	if !ok || len(refs) != 1 || refs[0].String() != "p.raw.orders" {
+		t.Errorf("Unexpected lineage: %v", refs)
+	}
+	if _, ok = cache.Lookup(v, "SELECT 2"); ok {
+		t.Errorf("The lineage of the old query should be stale")
+	}
+}
+
+func TestAddLineage(t *testing.T) {
+	g, configs := testGraph(t)
+	cache := &LineageCache{Views: make(map[string]*Lineage)}
+	// BigQuery reports the tables behind the views too.
+	cache.Record(configs["sales.weekly"], g.queries["sales.weekly"], []*bigquery.Table{
+		{ProjectID: "my-project", DatasetID: "sales", TableID: "daily"},
+		{ProjectID: "my-project", DatasetID: "raw", TableID: "orders"},
+		{ProjectID: "my-project", DatasetID: "raw", TableID: "customers"},
+	})
+	cache.Record(configs["sales.daily"], "SELECT old", []*bigquery.Table{{ProjectID: "my-project", DatasetID: "raw", TableID: "stale"}})
+	g.AddLineage(cache)
+
+	if names := viewNames(g.Upstreams(configs["sales.weekly"])); names != "sales.daily" {
+		t.Errorf("Unexpected upstream views: %s", names)
+	}
+	external := formatTableRefs(g.ExternalReferences(configs["sales.weekly"]))
+	if external != "other-project.sales.orders, my-project.raw.customers" {
+		t.Errorf("Unexpected external references: %s", external)
+	}
+	if external := formatTableRefs(g.ExternalReferences(configs["sales.daily"])); external != "" {
+		t.Errorf("The stale lineage shouldn't be added: %s", external)
+	}
+}
+
+func TestLineageCacheRecorded(t *testing.T) {
+	cache := &LineageCache{Views: map[string]*Lineage{"sales.old": {QueryHash: queryHash("SELECT 0")}}}
+	cache.Record(&ViewConfig{DatasetName: "sales", ViewName: "daily"}, "SELECT 1", nil)
+	recorded := cache.Recorded()
+	if len(recorded.Views) != 1 || recorded.Views["sales.daily"] == nil {
+		t.Errorf("only the recorded view should be returned but got %v", recorded.Views)
+	}
+}
@@ -0,0 +1,54 @@
+package bqv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FileError is an error which occurred while loading a file.
+// Line and Column are the position of the error in the file, or zero if it's unknown.
+type FileError struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *FileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Err.Error())
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// LoadError is the error returned when some files of the views failed to load.
+// It lists every failing file.
+type LoadError struct {
+	Errors []*FileError
+}
+
+func (e *LoadError) Error() string {
+	s := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		s = append(s, err.Error())
+	}
+	return fmt.Sprintf("%d files failed to load: %s", len(e.Errors), strings.Join(s, "; "))
+}
+
+// add appends err which occurred while loading the file at path.
+// The errors which already know their files keep them, and SchemaErrors are added one by one with their positions.
+func (e *LoadError) add(path string, err error) {
+	switch err := err.(type) {
+	case *FileError:
+		e.Errors = append(e.Errors, err)
+	case *LoadError:
+		e.Errors = append(e.Errors, err.Errors...)
+	case SchemaErrors:
+		for _, schemaErr := range err {
+			e.Errors = append(e.Errors, &FileError{Path: path, Line: schemaErr.Line, Column: schemaErr.Column, Err: errors.New(schemaErr.Message)})
+		}
+	default:
+		e.Errors = append(e.Errors, &FileError{Path: path, Err: err})
+	}
+}
@@ -0,0 +1,116 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateViewConfigsFromDatasetDirListsFailingFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "ok", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "bad_json", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "bad_json", "meta.json"), `{"description": `)
+	writeTestFile(t, filepath.Join(dir, "sales", "bad_template", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "bad_template", "meta.json"), `{"description": "{{.env"}`)
+	writeTestFile(t, filepath.Join(dir, "users", "ok", "query.sql"), "SELECT 1")
+
+	configs, err := CreateViewConfigsFromDatasetDir(dir)
+	if len(configs) != 2 {
+		t.Errorf("expected the 2 views loaded successfully, got %d", len(configs))
+	}
+	loadErr, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %v", err)
+	}
+	expected := []string{
+		filepath.Join(dir, "sales", "bad_json", "meta.json"),
+		filepath.Join(dir, "sales", "bad_template", "meta.json"),
+	}
+	if len(loadErr.Errors) != len(expected) {
+		t.Fatalf("expected %d failing files, got %v", len(expected), loadErr)
+	}
+	for i, path := range expected {
+		if loadErr.Errors[i].Path != path {
+			t.Errorf("expected %s, got %s", path, loadErr.Errors[i].Path)
+		}
+	}
+}
+
+func TestCreateViewConfigsFromDatasetDirWithoutErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "ok", "query.sql"), "SELECT 1")
+
+	configs, err := CreateViewConfigsFromDatasetDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(configs) != 1 {
+		t.Errorf("expected 1 view, got %d", len(configs))
+	}
+}
+
+func TestCreateViewConfigsFromDatasetDirRendersMetadataWithParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "view", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "view", "meta.json"), `{"description": "{{.env}}", "labels": {{.labels}}}`)
+
+	configs, err := CreateViewConfigsFromDatasetDir(dir)
+	if err != nil {
+		t.Fatalf("the templated metadata should be rendered with the params later: %s", err.Error())
+	}
+	if len(configs) != 1 {
+		t.Fatalf("expected 1 view, got %d", len(configs))
+	}
+	if md := configs[0].MetadataFromFile; md.Description != "" {
+		t.Errorf("the templated metadata shouldn't be rendered without the params but got %v", md)
+	}
+	md, err := configs[0].MetadataWithParam(map[string]string{"env": "prod", "labels": `{"env": "prod"}`})
+	if err != nil {
+		t.Fatalf("Failed to render metadata: %s", err.Error())
+	}
+	if md.Description != "prod" || md.Labels["env"] != "prod" {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+}
+
+func TestCreateViewConfigsFromDatasetDirReadsDatasetJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "dataset.json"), `{"location": "asia-northeast1", "labels": {"team": "sales"}}`)
+	writeTestFile(t, filepath.Join(dir, "sales", "view", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "users", "dataset.json"), `{"location": 1}`)
+	writeTestFile(t, filepath.Join(dir, "users", "view", "query.sql"), "SELECT 1")
+
+	configs, err := CreateViewConfigsFromDatasetDir(dir)
+	if len(configs) != 1 || configs[0].Dataset == nil || configs[0].Dataset.Location != "asia-northeast1" {
+		t.Fatalf("the view should have the dataset metadata but got %v", configs)
+	}
+	if md := configs[0].Dataset.datasetMetadata("sales"); md.Name != "sales" || md.Labels["team"] != "sales" {
+		t.Errorf("unexpected dataset metadata: %v", md)
+	}
+	loadErr, ok := err.(*LoadError)
+	if !ok || len(loadErr.Errors) != 1 || loadErr.Errors[0].Error() != filepath.Join(dir, "users", "dataset.json")+":1:14: location must be string but got number" {
+		t.Errorf("the invalid dataset.json should be reported but got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package bqv
+
+// MetadataSchema is the JSON Schema of meta.json.
+// Editors can use it to complete and check meta.json, and bqv validates meta.json against it.
+const MetadataSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "meta.json",
+  "description": "The metadata of a view managed by bqv.",
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "friendlyName": {
+      "description": "The descriptive name of the view. The view name is used if it's empty.",
+      "type": "string"
+    },
+    "description": {
+      "description": "The description of the view.",
+      "type": "string"
+    },
+    "schema": {
+      "description": "The documentation of the columns of the view.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name"],
+        "properties": {
+          "name": {
+            "description": "The name of the column.",
+            "type": "string"
+          },
+          "description": {
+            "description": "The description of the column.",
+            "type": "string"
+          },
+          "policyTags": {
+            "description": "The resource names of the Data Catalog policy tags attached to the column.",
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "attributes": {
+            "description": "Data-governance attributes such as sensitivity and owner. They are never sent to BigQuery.",
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            }
+          }
+        }
+      }
+    },
+    "labels": {
+      "description": "The labels of the view.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "expirationTime": {
+      "description": "The absolute time when the view expires in RFC3339 format.",
+      "type": "string"
+    },
+    "ttl": {
+      "description": "The lifetime of the view counted from the time it gets applied, such as \"720h\" or \"30d\".",
+      "type": "string"
+    },
+    "contract": {
+      "description": "The output columns the view promises to produce.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name", "type"],
+        "properties": {
+          "name": {
+            "description": "The name of the column.",
+            "type": "string"
+          },
+          "type": {
+            "description": "The BigQuery type of the column such as STRING, INT64, ARRAY<STRING> and STRUCT<id INT64, name STRING>.",
+            "type": "string"
+          },
+          "mode": {
+            "description": "NULLABLE, REQUIRED or REPEATED. NULLABLE is used if it's empty.",
+            "type": "string"
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+// DatasetSchema is the JSON Schema of dataset.json, which declares the metadata of a dataset in its directory.
+// bqv validates dataset.json against it and uses the metadata when it creates the dataset.
+const DatasetSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "dataset.json",
+  "description": "The metadata of a dataset bqv creates for the views in it.",
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "friendlyName": {
+      "description": "The descriptive name of the dataset.",
+      "type": "string"
+    },
+    "description": {
+      "description": "The description of the dataset.",
+      "type": "string"
+    },
+    "location": {
+      "description": "The geographic location of the dataset such as US and asia-northeast1. The default of the project is used if it's empty.",
+      "type": "string"
+    },
+    "labels": {
+      "description": "The labels of the dataset.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,147 @@
+package bqv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata is the metadata of a view described in meta.json.
+type Metadata struct {
+	// FriendlyName is the descriptive name of the view. The view name is used if it's empty.
+	FriendlyName string            `json:"friendlyName,omitempty"`
+	Description  string            `json:"description"`
+	Schema       []ColumnMetadata  `json:"schema"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	// ExpirationTime is the absolute time when the view expires in RFC3339 format.
+	ExpirationTime string `json:"expirationTime,omitempty"`
+	// TTL is the lifetime of the view counted from the time it gets applied, such as "720h" or "30d".
+	TTL string `json:"ttl,omitempty"`
+	// Contract is the output columns the view promises to produce.
+	Contract []ContractColumn `json:"contract,omitempty"`
+}
+
+// ColumnMetadata is the metadata of a column of a view described in meta.json.
+type ColumnMetadata struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// PolicyTags are the resource names of the Data Catalog policy tags attached to the column.
+	PolicyTags []string `json:"policyTags,omitempty"`
+	// Attributes are free-form data-governance attributes such as sensitivity and owner.
+	// They are kept in meta.json only and never sent to BigQuery.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Expiration returns the time when the view expires if it was applied at now.
+// Expiration returns the zero time if neither ExpirationTime nor TTL is given.
+func (m *Metadata) Expiration(now time.Time) (time.Time, error) {
+	if m.ExpirationTime != "" && m.TTL != "" {
+		return time.Time{}, fmt.Errorf("expirationTime and ttl can't be given at the same time")
+	}
+	if m.ExpirationTime != "" {
+		t, err := time.Parse(time.RFC3339, m.ExpirationTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid expirationTime(%s): %s", m.ExpirationTime, err.Error())
+		}
+		return t, nil
+	}
+	if m.TTL != "" {
+		ttl, err := parseTTL(m.TTL)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid ttl(%s): %s", m.TTL, err.Error())
+		}
+		return now.Add(ttl), nil
+	}
+	return time.Time{}, nil
+}
+
+// FriendlyNameOr returns FriendlyName or the given name if FriendlyName is empty.
+func (m *Metadata) FriendlyNameOr(name string) string {
+	if m.FriendlyName == "" {
+		return name
+	}
+	return m.FriendlyName
+}
+
+// parseTTL parses the duration in the format of time.ParseDuration which can also be a number of days like "30d".
+func parseTTL(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ColumnPolicyTags returns the policy tags declared in meta.json keyed by column name.
+func (m *Metadata) ColumnPolicyTags() map[string][]string {
+	ret := make(map[string][]string)
+	for _, column := range m.Schema {
+		if len(column.PolicyTags) == 0 {
+			continue
+		}
+		ret[column.Name] = column.PolicyTags
+	}
+	return ret
+}
+
+// ClassifiedColumn is a column whose governance attribute matches a classification.
+type ClassifiedColumn struct {
+	DatasetName string
+	ViewName    string
+	Column      ColumnMetadata
+	// Owner is the owner attribute of the column, or the owner label of the view if the column has none.
+	Owner string
+}
+
+// FindClassifiedColumns returns the columns whose attribute key has the given value.
+// The value is compared case-insensitively.
+func FindClassifiedColumns(configs []*ViewConfig, key, value string) []ClassifiedColumn {
+	ret := make([]ClassifiedColumn, 0)
+	for _, config := range configs {
+		for _, column := range config.MetadataFromFile.Schema {
+			if !strings.EqualFold(column.Attributes[key], value) {
+				continue
+			}
+			owner := column.Attributes["owner"]
+			if owner == "" {
+				owner = config.MetadataFromFile.Labels["owner"]
+			}
+			ret = append(ret, ClassifiedColumn{
+				DatasetName: config.DatasetName,
+				ViewName:    config.ViewName,
+				Column:      column,
+				Owner:       owner,
+			})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].DatasetName != ret[j].DatasetName {
+			return ret[i].DatasetName < ret[j].DatasetName
+		}
+		return ret[i].ViewName < ret[j].ViewName
+	})
+	return ret
+}
+
+func equalPolicyTags(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for column, tags := range a {
+		other, ok := b[column]
+		if !ok || len(tags) != len(other) {
+			return false
+		}
+		for i := range tags {
+			if tags[i] != other[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
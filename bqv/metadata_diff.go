@@ -0,0 +1,161 @@
+package bqv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// MetadataChangeType is the kind of a MetadataChange.
+type MetadataChangeType string
+
+// The kinds of changes of the metadata of a view.
+const (
+	FriendlyNameChanged      MetadataChangeType = "friendly name changed"
+	DescriptionChanged       MetadataChangeType = "description changed"
+	ExpirationChanged        MetadataChangeType = "expiration changed"
+	LabelAdded               MetadataChangeType = "label added"
+	LabelRemoved             MetadataChangeType = "label removed"
+	LabelChanged             MetadataChangeType = "label changed"
+	ColumnDescriptionChanged MetadataChangeType = "column description changed"
+	ColumnPolicyTagsChanged  MetadataChangeType = "column policy tags changed"
+)
+
+// MetadataChange is a change between the metadata of the actual view and the one made from the files.
+type MetadataChange struct {
+	Type MetadataChangeType
+	// Key is the label key or the column name the change is about. It's empty for the changes of the view itself.
+	Key string
+	Old string
+	New string
+}
+
+func (c MetadataChange) String() string {
+	switch c.Type {
+	case LabelAdded:
+		return fmt.Sprintf("%s: %s=%s", c.Type, c.Key, c.New)
+	case LabelRemoved:
+		return fmt.Sprintf("%s: %s=%s", c.Type, c.Key, c.Old)
+	}
+	if c.Key == "" {
+		return fmt.Sprintf("%s: %q -> %q", c.Type, c.Old, c.New)
+	}
+	return fmt.Sprintf("%s: %s: %q -> %q", c.Type, c.Key, c.Old, c.New)
+}
+
+// ttlTolerance is how far the expiration time of a view may be from the one its TTL gives
+// because of the time between the update of the expiration and the last modification of the view.
+const ttlTolerance = 10 * time.Minute
+
+// diffMetadata compares the metadata of the actual view with md field by field.
+// labels are the labels the view should have and currentTags are the policy tags attached to the columns of the view.
+func diffMetadata(m *bigquery.TableMetadata, md *Metadata, viewName string, labels map[string]string, currentTags map[string][]string, now time.Time) ([]MetadataChange, error) {
+	changes := make([]MetadataChange, 0)
+
+	// A view without a friendly name, such as one made by hand, is left alone unless meta.json gives one.
+	if name := md.FriendlyNameOr(viewName); m.Name != name && !(m.Name == "" && md.FriendlyName == "") {
+		changes = append(changes, MetadataChange{Type: FriendlyNameChanged, Old: m.Name, New: name})
+	}
+	if m.Description != md.Description {
+		changes = append(changes, MetadataChange{Type: DescriptionChanged, Old: m.Description, New: md.Description})
+	}
+
+	expiration, err := md.Expiration(now)
+	if err != nil {
+		return nil, err
+	}
+	if md.TTL != "" {
+		// A TTL makes the expiration time move whenever the view gets applied,
+		// so it's counted from the last modification of the view, which is when it was applied.
+		appliedAt := m.LastModifiedTime
+		if appliedAt.IsZero() {
+			appliedAt = now
+		}
+		gap := m.ExpirationTime.Sub(appliedAt.Add(expiration.Sub(now)))
+		if m.ExpirationTime.IsZero() || gap > ttlTolerance || gap < -ttlTolerance {
+			changes = append(changes, MetadataChange{Type: ExpirationChanged, Old: formatExpiration(m.ExpirationTime), New: "ttl " + md.TTL})
+		}
+	} else if !expiration.Equal(m.ExpirationTime) {
+		// The expiration is removed if meta.json gives neither expirationTime nor ttl.
+		changes = append(changes, MetadataChange{Type: ExpirationChanged, Old: formatExpiration(m.ExpirationTime), New: formatExpiration(expiration)})
+	}
+
+	changes = append(changes, diffLabels(m.Labels, labels)...)
+
+	for _, field := range m.Schema {
+		for _, column := range md.Schema {
+			if field.Name != column.Name {
+				continue
+			}
+			if field.Description != column.Description {
+				changes = append(changes, MetadataChange{Type: ColumnDescriptionChanged, Key: column.Name, Old: field.Description, New: column.Description})
+			}
+		}
+	}
+
+	newTags := md.ColumnPolicyTags()
+	for _, column := range sortedKeysOfTags(currentTags, newTags) {
+		if !equalPolicyTags(map[string][]string{column: currentTags[column]}, map[string][]string{column: newTags[column]}) {
+			changes = append(changes, MetadataChange{
+				Type: ColumnPolicyTagsChanged,
+				Key:  column,
+				Old:  strings.Join(currentTags[column], ","),
+				New:  strings.Join(newTags[column], ","),
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+func diffLabels(oldLabels, newLabels map[string]string) []MetadataChange {
+	changes := make([]MetadataChange, 0)
+	keys := make([]string, 0, len(oldLabels)+len(newLabels))
+	for key := range oldLabels {
+		keys = append(keys, key)
+	}
+	for key := range newLabels {
+		if _, ok := oldLabels[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, oldOK := oldLabels[key]
+		newValue, newOK := newLabels[key]
+		switch {
+		case !oldOK:
+			changes = append(changes, MetadataChange{Type: LabelAdded, Key: key, New: newValue})
+		case !newOK:
+			changes = append(changes, MetadataChange{Type: LabelRemoved, Key: key, Old: oldValue})
+		case oldValue != newValue:
+			changes = append(changes, MetadataChange{Type: LabelChanged, Key: key, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+func sortedKeysOfTags(a, b map[string][]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatExpiration(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,103 @@
+package bqv
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestDiffMetadata(t *testing.T) {
+	m := &bigquery.TableMetadata{
+		Name:        "test",
+		Description: "old",
+		Labels:      map[string]string{"ab": "c", "team": "sales"},
+		Schema: bigquery.Schema{
+			{Name: "id", Description: "identifier"},
+			{Name: "email", Description: "old email"},
+		},
+	}
+	md := &Metadata{
+		Description: "new",
+		Labels:      map[string]string{"a": "bc", "team": "sales"},
+		Schema: []ColumnMetadata{
+			{Name: "id", Description: "identifier"},
+			{Name: "email", Description: "new email"},
+		},
+	}
+
+	changes, err := diffMetadata(m, md, "test", md.Labels, nil, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to compare metadata: %s", err.Error())
+	}
+	expected := []MetadataChange{
+		{Type: DescriptionChanged, Old: "old", New: "new"},
+		{Type: LabelAdded, Key: "a", New: "bc"},
+		{Type: LabelRemoved, Key: "ab", Old: "c"},
+		{Type: ColumnDescriptionChanged, Key: "email", Old: "old email", New: "new email"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("%d changes were expected but got %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("%v was expected but got %v", expected[i], changes[i])
+		}
+	}
+}
+
+func TestDiffMetadataNoChange(t *testing.T) {
+	m := &bigquery.TableMetadata{
+		Name:   "test",
+		Labels: map[string]string{"team": "sales"},
+	}
+	md := &Metadata{Labels: map[string]string{"team": "sales"}}
+
+	changes, err := diffMetadata(m, md, "test", md.Labels, map[string][]string{}, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to compare metadata: %s", err.Error())
+	}
+	if len(changes) != 0 {
+		t.Errorf("No change was expected but got %v", changes)
+	}
+}
+
+func TestDiffMetadataExpiration(t *testing.T) {
+	now := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	appliedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := &bigquery.TableMetadata{
+		Name:             "test",
+		LastModifiedTime: appliedAt,
+		// The TTL of 30 days was applied a few seconds after the modification.
+		ExpirationTime: appliedAt.Add(30*24*time.Hour + 5*time.Second),
+	}
+
+	cases := []struct {
+		md       Metadata
+		expected string
+	}{
+		{Metadata{TTL: "30d"}, ""},
+		{Metadata{TTL: "7d"}, "ttl 7d"},
+		{Metadata{}, "never"},
+		{Metadata{ExpirationTime: "2020-01-31T00:00:05Z"}, ""},
+	}
+	for _, c := range cases {
+		changes, err := diffMetadata(m, &c.md, "test", nil, nil, now)
+		if err != nil {
+			t.Fatalf("Failed to compare metadata: %s", err.Error())
+		}
+		switch {
+		case c.expected == "" && len(changes) != 0:
+			t.Errorf("%v: no change was expected but got %v", c.md, changes)
+		case c.expected != "" && (len(changes) != 1 || changes[0].Type != ExpirationChanged || changes[0].New != c.expected):
+			t.Errorf("%v: the expiration should change to %s but got %v", c.md, c.expected, changes)
+		}
+	}
+
+	// A view without expiration gets one from the TTL.
+	m.ExpirationTime = time.Time{}
+	changes, err := diffMetadata(m, &Metadata{TTL: "30d"}, "test", nil, nil, now)
+	if err != nil || len(changes) != 1 || changes[0].Old != "never" {
+		t.Errorf("the TTL should be set but got %v, %v", changes, err)
+	}
+}
@@ -0,0 +1,305 @@
+package bqv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MetadataFormat is the format of a file which holds the metadata of a view.
+type MetadataFormat string
+
+// The formats of the metadata.
+const (
+	MetadataJSON MetadataFormat = "json"
+	MetadataYAML MetadataFormat = "yaml"
+)
+
+// MetadataSource is a template of the metadata of a view read from a file.
+// The metadata of a view can be split into meta.json, meta.yaml and the front-matter of query.sql.
+type MetadataSource struct {
+	Path     string
+	Format   MetadataFormat
+	Template string
+}
+
+// frontMatterPattern matches the front-matter comment block at the top of query.sql, which holds the metadata in YAML.
+//
+//	/*---
+//	description: ...
+//	---*/
+var frontMatterPattern = regexp.MustCompile(`^\s*/\*---[ \t]*\r?\n((?s:.*?)\r?\n)?---\*/[ \t]*(\r?\n|$)`)
+
+// splitFrontMatter returns the front-matter of the query and the query without it.
+// The front-matter is preceded by empty lines so that the line numbers in YAML errors match the ones in query.sql.
+// found is false if the query has no front-matter.
+func splitFrontMatter(query string) (frontMatter, rest string, found bool) {
+	m := frontMatterPattern.FindStringSubmatchIndex(query)
+	if m == nil {
+		return "", query, false
+	}
+	if m[2] >= 0 {
+		frontMatter = strings.Repeat("\n", strings.Count(query[:m[2]], "\n")) + query[m[2]:m[3]]
+	}
+	return frontMatter, query[m[1]:], true
+}
+
+// renderMetadata renders the sources with the params and merges them into a Metadata.
+// The objects such as labels are merged key by key and the columns such as schema are merged by their names,
+// so the sources may split them as long as they don't declare the same field differently.
+// It returns a *LoadError listing the problems of every source, including the fields declared differently in several sources.
+func renderMetadata(sources []MetadataSource, params map[string]string) (*Metadata, error) {
+	loadErr := new(LoadError)
+	merged := make(map[string]interface{})
+	declaredIn := make(map[string]string)
+	for _, source := range sources {
+		fields, err := source.render(params)
+		if err != nil {
+			loadErr.add(source.Path, err)
+			continue
+		}
+		m := &metadataMerger{source: source.Path, declaredIn: declaredIn, loadErr: loadErr}
+		m.mergeObject(merged, fields, "")
+	}
+	if len(loadErr.Errors) > 0 {
+		return nil, loadErr
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	md := new(Metadata)
+	if err := json.Unmarshal(data, md); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// metadataMerger merges the fields of a metadata source into the ones of the sources merged before.
+type metadataMerger struct {
+	source string
+	// declaredIn has the paths of the merged fields such as labels.team and schema[id].description and their sources.
+	declaredIn map[string]string
+	loadErr    *LoadError
+}
+
+func (m *metadataMerger) mergeObject(merged, fields map[string]interface{}, prefix string) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		m.mergeField(merged, key, fields[key], path)
+	}
+}
+
+func (m *metadataMerger) mergeField(merged map[string]interface{}, key string, value interface{}, path string) {
+	old, ok := merged[key]
+	if !ok {
+		merged[key] = value
+		m.declare(value, path)
+		return
+	}
+	if oldObject, ok := old.(map[string]interface{}); ok {
+		if object, ok := value.(map[string]interface{}); ok {
+			m.mergeObject(oldObject, object, path)
+			return
+		}
+	}
+	if oldColumns, ok := namedObjects(old); ok {
+		if columns, ok := namedObjects(value); ok {
+			merged[key] = m.mergeColumns(old.([]interface{}), oldColumns, columns, path)
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, value) {
+		m.loadErr.add(m.source, fmt.Errorf("field %q conflicts with the one in %s", path, m.declaredIn[path]))
+	}
+}
+
+// mergeColumns merges the columns into the merged ones with the same names and appends the others.
+func (m *metadataMerger) mergeColumns(merged []interface{}, mergedByName map[string]map[string]interface{}, columns map[string]map[string]interface{}, path string) []interface{} {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		columnPath := fmt.Sprintf("%s[%s]", path, name)
+		if column, ok := mergedByName[name]; ok {
+			m.mergeObject(column, columns[name], columnPath)
+			continue
+		}
+		merged = append(merged, columns[name])
+		m.declare(columns[name], columnPath)
+	}
+	return merged
+}
+
+// declare records the source of the field and the fields in it.
+func (m *metadataMerger) declare(value interface{}, path string) {
+	m.declaredIn[path] = m.source
+	if object, ok := value.(map[string]interface{}); ok {
+		for key, field := range object {
+			m.declare(field, path+"."+key)
+		}
+	}
+	if columns, ok := namedObjects(value); ok {
+		for name, column := range columns {
+			m.declare(column, fmt.Sprintf("%s[%s]", path, name))
+		}
+	}
+}
+
+// namedObjects returns the objects in the array by their names if all of them have distinct names like the columns of schema.
+func namedObjects(value interface{}) (map[string]map[string]interface{}, bool) {
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	ret := make(map[string]map[string]interface{})
+	for _, element := range array {
+		object, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := object["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		if _, ok := ret[name]; ok {
+			return nil, false
+		}
+		ret[name] = object
+	}
+	return ret, true
+}
+
+// checkMetadata checks the sources as far as it can before the params are known.
+// The syntax of the templates is checked, and the sources without template actions are parsed and merged.
+// It returns the merged metadata if no source has template actions, and nil otherwise.
+func checkMetadata(sources []MetadataSource) (*Metadata, error) {
+	loadErr := new(LoadError)
+	static := make([]MetadataSource, 0, len(sources))
+	for _, source := range sources {
+		ok, err := source.isStatic()
+		if err != nil {
+			loadErr.add(source.Path, err)
+			continue
+		}
+		if ok {
+			static = append(static, source)
+		}
+	}
+	if len(loadErr.Errors) > 0 {
+		return nil, loadErr
+	}
+	md, err := renderMetadata(static, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(static) < len(sources) {
+		return nil, nil
+	}
+	return md, nil
+}
+
+// isStatic returns true if the template of the source has no actions, so it renders the same with any params.
+func (s MetadataSource) isStatic() (bool, error) {
+	t, err := template.New("m").Parse(s.Template)
+	if err != nil {
+		return false, err
+	}
+	if t.Tree == nil {
+		return true, nil
+	}
+	for _, node := range t.Tree.Root.Nodes {
+		if node.Type() != parse.NodeText {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// render renders the template of the source with the params and returns its top-level fields
+// after validating them against MetadataSchema.
+func (s MetadataSource) render(params map[string]string) (map[string]interface{}, error) {
+	rendered, err := renderTemplate("m", s.Template, params)
+	if err != nil {
+		return nil, err
+	}
+	text := rendered.Text
+
+	var v interface{}
+	switch s.Format {
+	case MetadataJSON:
+		// The positions of the problems are the ones in the template, not in the rendered text.
+		if errs := validateJSON([]byte(text), metadataSchema, rendered.position); len(errs) > 0 {
+			return nil, errs
+		}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil, err
+		}
+	case MetadataYAML:
+		if err := yaml.Unmarshal([]byte(text), &v); err != nil {
+			return nil, err
+		}
+		if v, err = stringKeys(v); err != nil {
+			return nil, err
+		}
+		if v == nil {
+			// An empty YAML document declares no field.
+			v = map[string]interface{}{}
+		}
+		if errs := validateMetadataValue(v); len(errs) > 0 {
+			return nil, errs
+		}
+	default:
+		return nil, fmt.Errorf("unknown metadata format: %s", s.Format)
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// stringKeys converts the maps decoded from YAML into the ones keyed by string as decoded from JSON.
+func stringKeys(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("key(%v) must be a string", key)
+			}
+			converted, err := stringKeys(value)
+			if err != nil {
+				return nil, err
+			}
+			ret[s] = converted
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := stringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = converted
+		}
+		return ret, nil
+	}
+	return v, nil
+}
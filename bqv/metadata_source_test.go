@@ -0,0 +1,148 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	query := "/*---\ndescription: |\n  Sales of {{.env}}.\n---*/\nSELECT 1\n"
+	frontMatter, rest, found := splitFrontMatter(query)
+	if !found {
+		t.Fatal("the front-matter should be found")
+	}
+	if frontMatter != "\ndescription: |\n  Sales of {{.env}}.\n" {
+		t.Errorf("unexpected front-matter: %q", frontMatter)
+	}
+	if rest != "SELECT 1\n" {
+		t.Errorf("the front-matter should be stripped but got %q", rest)
+	}
+
+	for _, query := range []string{"SELECT 1", "/* comment */\nSELECT 1", "SELECT 1\n/*---\na: b\n---*/"} {
+		if _, rest, found := splitFrontMatter(query); found || rest != query {
+			t.Errorf("no front-matter should be found in %q", query)
+		}
+	}
+}
+
+func TestRenderMetadata(t *testing.T) {
+	sources := []MetadataSource{
+		{Path: "meta.json", Format: MetadataJSON, Template: `{"labels": {"env": "{{.env}}"}}`},
+		{Path: "meta.yaml", Format: MetadataYAML, Template: "# Comments are allowed.\nexpirationTime: 2019-02-01T00:00:00Z\nschema:\n  - name: id\n    description: |\n      The ID\n      of the sale.\n"},
+		{Path: "query.sql", Format: MetadataYAML, Template: "description: Sales in {{.env}}\nlabels:\n  env: {{.env}}\n"},
+	}
+	md, err := renderMetadata(sources, map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatalf("Failed to render metadata: %s", err.Error())
+	}
+	if md.Description != "Sales in prod" || md.Labels["env"] != "prod" || md.ExpirationTime != "2019-02-01T00:00:00Z" {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+	if len(md.Schema) != 1 || md.Schema[0].Description != "The ID\nof the sale.\n" {
+		t.Errorf("unexpected schema: %v", md.Schema)
+	}
+}
+
+func TestRenderMetadataReportsConflicts(t *testing.T) {
+	sources := []MetadataSource{
+		{Path: "meta.json", Format: MetadataJSON, Template: `{"description": "sales"}`},
+		{Path: "meta.yaml", Format: MetadataYAML, Template: "description: orders\nlabels:\n  count: 1\n"},
+		{Path: "query.sql", Format: MetadataYAML, Template: "descripton: typo\n"},
+	}
+	_, err := renderMetadata(sources, nil)
+	loadErr, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %v", err)
+	}
+	expected := []string{
+		"meta.yaml: labels.count must be string but got number",
+		`query.sql: unknown field "descripton"`,
+	}
+	if len(loadErr.Errors) != len(expected) {
+		t.Fatalf("%d errors were expected but got %v", len(expected), loadErr)
+	}
+	for i := range expected {
+		if loadErr.Errors[i].Error() != expected[i] {
+			t.Errorf("expected %q but got %q", expected[i], loadErr.Errors[i].Error())
+		}
+	}
+
+	sources[1].Template = "description: orders\n"
+	sources = sources[:2]
+	_, err = renderMetadata(sources, nil)
+	if err == nil || err.Error() != `1 files failed to load: meta.yaml: field "description" conflicts with the one in meta.json` {
+		t.Errorf("the conflict should be reported but got %v", err)
+	}
+}
+
+func TestRenderMetadataMergesNestedFields(t *testing.T) {
+	sources := []MetadataSource{
+		{Path: "meta.yaml", Format: MetadataYAML, Template: "labels:\n  team: sales\nschema:\n  - name: id\n    description: The ID\n  - name: amount\n"},
+		{Path: "query.sql", Format: MetadataYAML, Template: "labels:\n  env: prod\nschema:\n  - name: amount\n    description: The amount\n  - name: id\n    policyTags: [tag]\n  - name: note\n"},
+	}
+	md, err := renderMetadata(sources, nil)
+	if err != nil {
+		t.Fatalf("the fields which don't overlap should be merged: %s", err.Error())
+	}
+	if len(md.Labels) != 2 || md.Labels["team"] != "sales" || md.Labels["env"] != "prod" {
+		t.Errorf("unexpected labels: %v", md.Labels)
+	}
+	if len(md.Schema) != 3 || md.Schema[0].Name != "id" || md.Schema[1].Name != "amount" || md.Schema[2].Name != "note" {
+		t.Fatalf("unexpected schema: %v", md.Schema)
+	}
+	if md.Schema[0].Description != "The ID" || len(md.Schema[0].PolicyTags) != 1 || md.Schema[1].Description != "The amount" {
+		t.Errorf("the columns should be merged by their names but got %v", md.Schema)
+	}
+
+	sources[1].Template = "labels:\n  team: marketing\nschema:\n  - name: id\n    description: Another ID\n"
+	_, err = renderMetadata(sources, nil)
+	loadErr, ok := err.(*LoadError)
+	if !ok {
+		t.Fatalf("expected *LoadError, got %v", err)
+	}
+	expected := []string{
+		`query.sql: field "labels.team" conflicts with the one in meta.yaml`,
+		`query.sql: field "schema[id].description" conflicts with the one in meta.yaml`,
+	}
+	if len(loadErr.Errors) != len(expected) {
+		t.Fatalf("%d errors were expected but got %v", len(expected), loadErr)
+	}
+	for i := range expected {
+		if loadErr.Errors[i].Error() != expected[i] {
+			t.Errorf("expected %q but got %q", expected[i], loadErr.Errors[i].Error())
+		}
+	}
+}
+
+func TestCreateViewConfigsFromDatasetDirWithFrontMatter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "view", "query.sql"), "/*---\ndescription: sales\n---*/\nSELECT 1\n")
+	writeTestFile(t, filepath.Join(dir, "sales", "view", "meta.yaml"), "labels:\n  env: prod\n")
+	writeTestFile(t, filepath.Join(dir, "sales", "broken", "query.sql"), "/*---\ndescription: sales\nlabels: [\n---*/\nSELECT 1\n")
+
+	configs, err := CreateViewConfigsFromDatasetDir(dir)
+	if len(configs) != 1 {
+		t.Fatalf("expected 1 view, got %d", len(configs))
+	}
+	if configs[0].Query != "SELECT 1\n" {
+		t.Errorf("the front-matter should be stripped but got %q", configs[0].Query)
+	}
+	if md := configs[0].MetadataFromFile; md.Description != "sales" || md.Labels["env"] != "prod" {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+	loadErr, ok := err.(*LoadError)
+	if !ok || len(loadErr.Errors) != 1 {
+		t.Fatalf("the broken front-matter should be reported but got %v", err)
+	}
+	if msg := loadErr.Errors[0].Error(); !strings.HasPrefix(msg, filepath.Join(dir, "sales", "broken", "query.sql")) || !strings.Contains(msg, "line 3") {
+		t.Errorf("the error should point at query.sql line 3 but got %s", msg)
+	}
+}
@@ -0,0 +1,90 @@
+package bqv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindClassifiedColumns(t *testing.T) {
+	v := &ViewConfig{DatasetName: "sales", ViewName: "customers"}
+	v.MetadataFromFile.Labels = map[string]string{"owner": "sales-team"}
+	v.MetadataFromFile.Schema = []ColumnMetadata{
+		{Name: "id"},
+		{Name: "email", Attributes: map[string]string{"sensitivity": "PII", "owner": "crm"}},
+		{Name: "country", Attributes: map[string]string{"sensitivity": "public"}},
+		{Name: "phone", Attributes: map[string]string{"sensitivity": "pii"}},
+	}
+
+	columns := FindClassifiedColumns([]*ViewConfig{v}, "sensitivity", "pii")
+	if len(columns) != 2 {
+		t.Fatalf("2 columns should have been found but got %d", len(columns))
+	}
+	if columns[0].Column.Name != "email" || columns[0].Owner != "crm" {
+		t.Errorf("email owned by crm should have been found but got %v", columns[0])
+	}
+	if columns[1].Column.Name != "phone" || columns[1].Owner != "sales-team" {
+		t.Errorf("the owner of phone should be the owner label of the view but got %v", columns[1])
+	}
+}
+
+func TestEqualPolicyTags(t *testing.T) {
+	a := map[string][]string{"email": {"projects/p/locations/us/taxonomies/1/policyTags/2"}}
+	b := map[string][]string{"email": {"projects/p/locations/us/taxonomies/1/policyTags/2"}}
+	if !equalPolicyTags(a, b) {
+		t.Error("policy tags should be equal")
+	}
+	if equalPolicyTags(a, map[string][]string{}) {
+		t.Error("policy tags shouldn't be equal")
+	}
+	b["email"] = []string{"projects/p/locations/us/taxonomies/1/policyTags/3"}
+	if equalPolicyTags(a, b) {
+		t.Error("policy tags shouldn't be equal")
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	m := &Metadata{TTL: "30d"}
+	expiration, err := m.Expiration(now)
+	if err != nil {
+		t.Fatalf("Failed to get expiration: %s", err.Error())
+	}
+	if !expiration.Equal(now.Add(30 * 24 * time.Hour)) {
+		t.Errorf("Unexpected expiration: %s", expiration)
+	}
+
+	m = &Metadata{ExpirationTime: "2019-02-01T00:00:00Z"}
+	expiration, err = m.Expiration(now)
+	if err != nil {
+		t.Fatalf("Failed to get expiration: %s", err.Error())
+	}
+	if !expiration.Equal(time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected expiration: %s", expiration)
+	}
+
+	m = &Metadata{ExpirationTime: "2019-02-01T00:00:00Z", TTL: "1h"}
+	if _, err = m.Expiration(now); err == nil {
+		t.Error("expirationTime and ttl shouldn't be accepted at the same time")
+	}
+}
+
+func TestMetadataWithParam(t *testing.T) {
+	v := &ViewConfig{
+		DatasetName: "test",
+		ViewName:    "test",
+		MetadataSources: []MetadataSource{
+			{Path: "meta.json", Format: MetadataJSON, Template: `{"friendlyName": "Test ({{.env}})", "labels": {"env": "{{.env}}"}}`},
+		},
+	}
+	md, err := v.MetadataWithParam(map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatalf("Failed to render metadata: %s", err.Error())
+	}
+	if md.FriendlyName != "Test (prod)" {
+		t.Errorf("Unexpected friendly name: %s", md.FriendlyName)
+	}
+	if md.Labels["env"] != "prod" {
+		t.Errorf("Unexpected label: %s", md.Labels["env"])
+	}
+}
@@ -0,0 +1,297 @@
+package bqv
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxNestingDepth is the maximum number of levels of nested views BigQuery accepts.
+const MaxNestingDepth = 16
+
+// Cycle is a circular reference between the managed views such as ["a.x", "a.y", "a.x"].
+type Cycle []string
+
+func (c Cycle) String() string {
+	return "circular reference: " + strings.Join(c, " -> ")
+}
+
+// NestingIssue is a view which nests more levels of views than the limit.
+// Path is the deepest chain of the views to the view, which starts with an external view if it's the deepest.
+type NestingIssue struct {
+	View  string
+	Depth int
+	Limit int
+	Path  []string
+}
+
+func (i NestingIssue) String() string {
+	return fmt.Sprintf("view(%s) nests %d levels of views which is more than the limit %d: %s", i.View, i.Depth, i.Limit, strings.Join(i.Path, " -> "))
+}
+
+// Cycles returns the circular references between the managed views, one for each group of the views referencing each other.
+func (g *DependencyGraph) Cycles() []Cycle {
+	ret := make([]Cycle, 0)
+	for _, component := range g.stronglyConnectedComponents() {
+		members := make(map[string]bool)
+		for _, key := range component {
+			members[key] = true
+		}
+		start := component[0]
+		if len(component) == 1 && !contains(g.upstreams[start], start) {
+			continue
+		}
+		ret = append(ret, g.cyclePath(start, members))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i][0] < ret[j][0] })
+	return ret
+}
+
+// stronglyConnectedComponents returns the groups of the views which reach each other by Tarjan's algorithm.
+// The keys in each group are sorted.
+func (g *DependencyGraph) stronglyConnectedComponents() [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	ret := make([][]string, 0)
+
+	var visit func(key string)
+	visit = func(key string) {
+		index[key] = len(index)
+		lowlink[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+		for _, upstream := range g.upstreams[key] {
+			if _, ok := index[upstream]; !ok {
+				visit(upstream)
+				if lowlink[upstream] < lowlink[key] {
+					lowlink[key] = lowlink[upstream]
+				}
+			} else if onStack[upstream] && index[upstream] < lowlink[key] {
+				lowlink[key] = index[upstream]
+			}
+		}
+		if lowlink[key] != index[key] {
+			return
+		}
+		component := make([]string, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == key {
+				break
+			}
+		}
+		sort.Strings(component)
+		ret = append(ret, component)
+	}
+
+	keys := make([]string, 0, len(g.configs))
+	for key := range g.configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := index[key]; !ok {
+			visit(key)
+		}
+	}
+	return ret
+}
+
+// cyclePath returns the shortest path from start back to start through the members.
+func (g *DependencyGraph) cyclePath(start string, members map[string]bool) Cycle {
+	parents := make(map[string]string)
+	queue := []string{start}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, upstream := range g.upstreams[key] {
+			if upstream == start {
+				path := Cycle{start}
+				for k := key; k != start; k = parents[k] {
+					path = append(Cycle{k}, path...)
+				}
+				return append(Cycle{start}, path...)
+			}
+			if _, ok := parents[upstream]; ok || !members[upstream] {
+				continue
+			}
+			parents[upstream] = key
+			queue = append(queue, upstream)
+		}
+	}
+	return Cycle{start}
+}
+
+// CheckNesting returns the managed views which nest more levels of views than the limit.
+// A view selecting only from tables is at the level 1.
+// externalDepths has the levels of the views bqv doesn't manage keyed by "project.dataset.view",
+// and the other external references are regarded as tables. The views in cycles are left to Cycles.
+func (g *DependencyGraph) CheckNesting(limit int, externalDepths map[string]int) []NestingIssue {
+	depths := make(map[string]int)
+	paths := make(map[string][]string)
+	visiting := make(map[string]bool)
+
+	var depth func(key string) int
+	depth = func(key string) int {
+		if d, ok := depths[key]; ok {
+			return d
+		}
+		if visiting[key] {
+			return 0
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+
+		d, path := 0, []string{}
+		for _, ref := range g.external[key] {
+			name := g.resolve(ref).String()
+			if ed := externalDepths[name]; ed > d {
+				d, path = ed, []string{name}
+			}
+		}
+		for _, upstream := range g.upstreams[key] {
+			if ud := depth(upstream); ud > d {
+				d, path = ud, paths[upstream]
+			}
+		}
+		depths[key] = d + 1
+		paths[key] = append(append([]string{}, path...), key)
+		return depths[key]
+	}
+
+	ret := make([]NestingIssue, 0)
+	for _, key := range sortedViewKeys(g.configs) {
+		if d := depth(key); d > limit {
+			ret = append(ret, NestingIssue{View: key, Depth: d, Limit: limit, Path: paths[key]})
+		}
+	}
+	return ret
+}
+
+// resolve fills the project of the reference with the project of the graph.
+func (g *DependencyGraph) resolve(ref TableRef) TableRef {
+	if ref.ProjectID == "" {
+		ref.ProjectID = g.projectID
+	}
+	return ref
+}
+
+func sortedViewKeys(configs map[string]*ViewConfig) []string {
+	keys := make([]string, 0, len(configs))
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ExternalViewDepths asks BigQuery which of the external references of the views and their managed upstream views are views
+// and returns their levels of nesting for CheckNesting. The views they select from are followed until the tables or the limit of BigQuery.
+// A reference whose metadata can't be read is regarded as a table with a warning.
+func (g *DependencyGraph) ExternalViewDepths(ctx context.Context, client *bigquery.Client, views []*ViewConfig) map[string]int {
+	depths := make(map[string]int)
+	visiting := make(map[string]bool)
+
+	var depth func(ref TableRef) int
+	depth = func(ref TableRef) int {
+		name := ref.String()
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		if visiting[name] || strings.HasSuffix(ref.TableID, "*") {
+			return 0
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		dataset := client.Dataset(ref.DatasetID)
+		if ref.ProjectID != "" {
+			dataset = client.DatasetInProject(ref.ProjectID, ref.DatasetID)
+		}
+		m, err := dataset.Table(ref.TableID).Metadata(ctx)
+		if err != nil {
+			if !hasStatusCode(err, http.StatusNotFound) {
+				logrus.Warnf("Failed to get the metadata of table(%s), so it's regarded as a table: %s", name, err.Error())
+			}
+			depths[name] = 0
+			return 0
+		}
+		d := 0
+		if m.Type == bigquery.ViewTable {
+			// The names without the project in the query of a view mean the tables in the project of the view.
+			refs, err := TableRefs(m.ViewQuery, ref.ProjectID)
+			if err != nil {
+				logrus.Warnf("Failed to parse the query of view(%s): %s", name, err.Error())
+			}
+			for _, upstream := range refs {
+				if ud := depth(upstream); ud > d {
+					d = ud
+				}
+			}
+			d++
+		}
+		depths[name] = d
+		return d
+	}
+
+	// Only the external references the views depend on directly or through their managed upstream views are looked up.
+	keys := make(map[string]bool)
+	for _, v := range views {
+		keys[viewKey(v.DatasetName, v.ViewName)] = true
+		for _, upstream := range g.AllUpstreams(v) {
+			keys[viewKey(upstream.DatasetName, upstream.ViewName)] = true
+		}
+	}
+	for _, key := range sortedViewKeys(g.configs) {
+		if !keys[key] {
+			continue
+		}
+		for _, ref := range g.external[key] {
+			depth(g.resolve(ref))
+		}
+	}
+	return depths
+}
+
+// ValidateDependencies checks the references between the views defined in the filesystem fsys without accessing BigQuery.
+// name is the path of the filesystem shown in the problems. It reports the cycles and the views which nest more levels of managed views than maxDepth.
+// The views whose query can't be rendered are skipped because Validate reports them.
+func ValidateDependencies(fsys fs.FS, name string, params map[string]string, maxDepth int) []Problem {
+	tree := viewTree{fsys: fsys, name: name}
+	loaded, _ := CreateViewConfigsFromFS(fsys, name)
+	configs := make([]*ViewConfig, 0, len(loaded))
+	for _, config := range loaded {
+		if _, err := config.QueryWithParam(params); err == nil {
+			configs = append(configs, config)
+		}
+	}
+	g, err := NewDependencyGraph(configs, params, "")
+	if err != nil {
+		return []Problem{{Path: name, Message: err.Error()}}
+	}
+
+	problems := make([]Problem, 0)
+	viewDir := func(key string) string {
+		config := g.configs[key]
+		return tree.path(path.Join(config.DatasetName, config.ViewName))
+	}
+	for _, cycle := range g.Cycles() {
+		problems = append(problems, Problem{Path: viewDir(cycle[0]), Message: cycle.String()})
+	}
+	for _, issue := range g.CheckNesting(maxDepth, nil) {
+		problems = append(problems, Problem{Path: viewDir(issue.View), Message: issue.String()})
+	}
+	return problems
+}
@@ -0,0 +1,132 @@
+package bqv
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/option"
+)
+
+func TestCycles(t *testing.T) {
+	configs := []*ViewConfig{
+		{DatasetName: "a", ViewName: "x", Query: "SELECT * FROM a.y"},
+		{DatasetName: "a", ViewName: "y", Query: "SELECT * FROM a.z JOIN raw.t USING (id)"},
+		{DatasetName: "a", ViewName: "z", Query: "SELECT * FROM a.x"},
+		{DatasetName: "b", ViewName: "self", Query: "SELECT * FROM b.self"},
+		{DatasetName: "b", ViewName: "ok", Query: "SELECT * FROM a.x"},
+	}
+	g, err := NewDependencyGraph(configs, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to build the graph: %s", err.Error())
+	}
+	cycles := g.Cycles()
+	if len(cycles) != 2 {
+		t.Fatalf("Unexpected cycles: %v", cycles)
+	}
+	if s := cycles[0].String(); s != "circular reference: a.x -> a.y -> a.z -> a.x" {
+		t.Errorf("Unexpected cycle: %s", s)
+	}
+	if s := cycles[1].String(); s != "circular reference: b.self -> b.self" {
+		t.Errorf("Unexpected cycle: %s", s)
+	}
+}
+
+func TestCheckNesting(t *testing.T) {
+	g, _ := testGraph(t)
+	if issues := g.CheckNesting(MaxNestingDepth, nil); len(issues) != 0 {
+		t.Errorf("No issue was expected but got %v", issues)
+	}
+
+	issues := g.CheckNesting(3, nil)
+	if len(issues) != 1 || issues[0].String() != "view(report.latest) nests 4 levels of views which is more than the limit 3: sales.orders -> sales.daily -> sales.weekly -> report.latest" {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+
+	issues = g.CheckNesting(MaxNestingDepth, map[string]int{"other-project.sales.orders": 15})
+	if len(issues) != 1 || issues[0].View != "report.latest" || issues[0].Depth != 17 {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+	if len(issues) == 1 && issues[0].Path[0] != "other-project.sales.orders" {
+		t.Errorf("The path should start from the external view: %v", issues[0].Path)
+	}
+}
+
+func TestExternalViewDepths(t *testing.T) {
+	// The fake BigQuery has the view ext.v over the table ext.t and denies the access to ext.denied.
+	requested := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/datasets/ext/tables/v"):
+			fmt.Fprint(w, `{"type": "VIEW", "view": {"query": "SELECT * FROM ext.t"}}`)
+		case strings.HasSuffix(r.URL.Path, "/datasets/ext/tables/t"):
+			fmt.Fprint(w, `{"type": "TABLE"}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": 403, "message": "Access Denied", "errors": [{"reason": "accessDenied", "message": "Access Denied"}]}}`)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "p", option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create a client: %s", err.Error())
+	}
+
+	configs := []*ViewConfig{
+		{DatasetName: "a", ViewName: "x", Query: "SELECT * FROM a.y JOIN ext.denied USING (id)"},
+		{DatasetName: "a", ViewName: "y", Query: "SELECT * FROM ext.v"},
+		{DatasetName: "a", ViewName: "other", Query: "SELECT * FROM ext.unrelated"},
+	}
+	g, err := NewDependencyGraph(configs, nil, "p")
+	if err != nil {
+		t.Fatalf("Failed to build the graph: %s", err.Error())
+	}
+	depths := g.ExternalViewDepths(ctx, client, configs[:1])
+	if depths["p.ext.v"] != 1 || depths["p.ext.t"] != 0 {
+		t.Errorf("The depth of the view should survive the denied reference but got %v", depths)
+	}
+	if d, ok := depths["p.ext.denied"]; !ok || d != 0 {
+		t.Errorf("The denied reference should be regarded as a table but got %v", depths)
+	}
+	for _, path := range requested {
+		if strings.HasSuffix(path, "/unrelated") {
+			t.Errorf("The references out of the upstreams of the selected views shouldn't be looked up: %v", requested)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 1; i <= 3; i++ {
+		writeTestFile(t, filepath.Join(dir, "chain", fmt.Sprintf("v%d", i), "query.sql"), fmt.Sprintf("SELECT * FROM chain.v%d", i-1))
+	}
+	writeTestFile(t, filepath.Join(dir, "loop", "a", "query.sql"), "SELECT * FROM loop.b")
+	writeTestFile(t, filepath.Join(dir, "loop", "b", "query.sql"), "SELECT * FROM `loop.a`")
+	writeTestFile(t, filepath.Join(dir, "loop", "broken", "query.sql"), "SELECT {{.env")
+
+	problems := ValidateDependencies(os.DirFS(dir), dir, map[string]string{}, 2)
+	if len(problems) != 2 {
+		t.Fatalf("2 problems were expected but got %v", problems)
+	}
+	if problems[0].Path != filepath.Join(dir, "loop", "a") || problems[0].Message != "circular reference: loop.a -> loop.b -> loop.a" {
+		t.Errorf("Unexpected problem: %s", problems[0])
+	}
+	if problems[1].Path != filepath.Join(dir, "chain", "v3") {
+		t.Errorf("Unexpected problem: %s", problems[1])
+	}
+}
@@ -0,0 +1,211 @@
+package bqv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// The version of the BigQuery client library we depend on doesn't know policy tags,
+// so they are read and written through the REST API directly.
+const defaultEndpoint = "https://www.googleapis.com/bigquery/v2/"
+
+// restClient sends the requests to the REST API with the options the BigQuery client was created with.
+type restClient struct {
+	http     *http.Client
+	endpoint string
+}
+
+// restClients has the REST clients of the BigQuery clients.
+var restClients = struct {
+	sync.Mutex
+	clients map[*bigquery.Client]*restClient
+}{clients: make(map[*bigquery.Client]*restClient)}
+
+// NewClient creates a BigQuery client in the same way as bigquery.NewClient.
+// The policy tags, which the client library doesn't know, are read and written with the same credentials, endpoint and options.
+// The clients created by bigquery.NewClient use the application default credentials for them.
+func NewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (*bigquery.Client, error) {
+	client, err := bigquery.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := newRestClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	restClients.Lock()
+	defer restClients.Unlock()
+	restClients.clients[client] = rest
+	return client, nil
+}
+
+func newRestClient(ctx context.Context, opts ...option.ClientOption) (*restClient, error) {
+	o := append([]option.ClientOption{option.WithEndpoint(defaultEndpoint), option.WithScopes(bigquery.Scope)}, opts...)
+	client, endpoint, err := htransport.NewClient(ctx, o...)
+	if err != nil {
+		return nil, err
+	}
+	return &restClient{http: client, endpoint: endpoint}, nil
+}
+
+// restClientOf returns the REST client of the BigQuery client, which is built once.
+func restClientOf(ctx context.Context, client *bigquery.Client) (*restClient, error) {
+	restClients.Lock()
+	defer restClients.Unlock()
+	if rest, ok := restClients.clients[client]; ok {
+		return rest, nil
+	}
+	rest, err := newRestClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restClients.clients[client] = rest
+	return rest, nil
+}
+
+func (c *restClient) tableURL(view *bigquery.Table) string {
+	return fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s", strings.TrimRight(c.endpoint, "/"), view.ProjectID, view.DatasetID, view.TableID)
+}
+
+type restTable struct {
+	ETag   string `json:"etag"`
+	Schema struct {
+		Fields []map[string]interface{} `json:"fields"`
+	} `json:"schema"`
+}
+
+// getColumnPolicyTags returns the policy tags attached to the top-level columns of the view.
+func getColumnPolicyTags(ctx context.Context, client *bigquery.Client, view *bigquery.Table) (map[string][]string, error) {
+	rest, err := restClientOf(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	t, err := rest.getTable(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]string)
+	for _, field := range t.Schema.Fields {
+		tags := policyTagNames(field)
+		if len(tags) == 0 {
+			continue
+		}
+		name, _ := field["name"].(string)
+		ret[name] = tags
+	}
+	return ret, nil
+}
+
+// setColumnPolicyTags replaces the policy tags of the top-level columns of the view with the given ones.
+func setColumnPolicyTags(ctx context.Context, client *bigquery.Client, view *bigquery.Table, tags map[string][]string) error {
+	rest, err := restClientOf(ctx, client)
+	if err != nil {
+		return err
+	}
+	t, err := rest.getTable(ctx, view)
+	if err != nil {
+		return err
+	}
+	for _, field := range t.Schema.Fields {
+		name, _ := field["name"].(string)
+		if names, ok := tags[name]; ok {
+			field["policyTags"] = map[string]interface{}{"names": names}
+		} else {
+			delete(field, "policyTags")
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"schema": t.Schema})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPatch, rest.tableURL(view), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", t.ETag)
+	res, err := rest.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	logrus.Debugf("Policy tags of view(%s.%s) were updated", view.DatasetID, view.TableID)
+	return nil
+}
+
+func (c *restClient) getTable(ctx context.Context, view *bigquery.Table) (*restTable, error) {
+	req, err := http.NewRequest(http.MethodGet, c.tableURL(view), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	t := new(restTable)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkPolicyTagColumns returns an error if a column the policy tags are declared for isn't a top-level column of the schema.
+// The policy tags of the nested columns aren't supported.
+func checkPolicyTagColumns(schema bigquery.Schema, tags map[string][]string) error {
+	columns := make(map[string]bool)
+	for _, field := range schema {
+		columns[field.Name] = true
+	}
+	for _, column := range sortedKeysOfTags(tags, nil) {
+		if columns[column] {
+			continue
+		}
+		if strings.Contains(column, ".") {
+			return fmt.Errorf("policy tags of the nested column(%s) aren't supported", column)
+		}
+		return fmt.Errorf("column(%s) which has policy tags doesn't exist", column)
+	}
+	return nil
+}
+
+func policyTagNames(field map[string]interface{}) []string {
+	policyTags, ok := field["policyTags"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	names, ok := policyTags["names"].([]interface{})
+	if !ok {
+		return nil
+	}
+	ret := make([]string, 0, len(names))
+	for _, name := range names {
+		if s, ok := name.(string); ok {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,63 @@
+package bqv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/option"
+)
+
+func TestGetColumnPolicyTagsUsesClientOptions(t *testing.T) {
+	requested := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"schema": {"fields": [{"name": "email", "policyTags": {"names": ["tag"]}}, {"name": "id"}]}}`)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	client, err := NewClient(ctx, "p", option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create a client: %s", err.Error())
+	}
+
+	view := client.Dataset("sales").Table("customers")
+	for i := 0; i < 2; i++ {
+		tags, err := getColumnPolicyTags(ctx, client, view)
+		if err != nil {
+			t.Fatalf("Failed to get the policy tags: %s", err.Error())
+		}
+		if len(tags) != 1 || tags["email"][0] != "tag" {
+			t.Errorf("Unexpected policy tags: %v", tags)
+		}
+	}
+	if len(requested) != 2 || !strings.HasSuffix(requested[0], "/projects/p/datasets/sales/tables/customers") {
+		t.Errorf("The endpoint of the client should be used but got %v", requested)
+	}
+	restClients.Lock()
+	defer restClients.Unlock()
+	if _, ok := restClients.clients[client]; !ok {
+		t.Error("The REST client should be kept for the client")
+	}
+}
+
+func TestCheckPolicyTagColumns(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "email", Type: bigquery.StringFieldType},
+		{Name: "payload", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "phone", Type: bigquery.StringFieldType}}},
+	}
+	if err := checkPolicyTagColumns(schema, map[string][]string{"email": {"tag"}}); err != nil {
+		t.Errorf("The top-level column should be accepted: %s", err.Error())
+	}
+	if err := checkPolicyTagColumns(schema, map[string][]string{"payload.phone": {"tag"}}); err == nil || !strings.Contains(err.Error(), "nested column(payload.phone)") {
+		t.Errorf("The nested column should be rejected but got %v", err)
+	}
+	if err := checkPolicyTagColumns(schema, map[string][]string{"missing": {"tag"}}); err == nil {
+		t.Error("The missing column should be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package bqv
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizeQuery returns the query without comments and insignificant whitespace,
+// so queries which differ only in formatting become the same string.
+// String literals and quoted identifiers are kept as they are.
+func NormalizeQuery(q string) string {
+	var buf strings.Builder
+	pendingSpace := false
+	var last rune
+	rs := []rune(q)
+
+	write := func(token []rune) {
+		if pendingSpace && last != 0 && !isPunctuation(last) && !isPunctuation(token[0]) {
+			buf.WriteRune(' ')
+		}
+		pendingSpace = false
+		buf.WriteString(string(token))
+		last = token[len(token)-1]
+	}
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			pendingSpace = true
+			i++
+		case r == '#' || (r == '-' && i+1 < len(rs) && rs[i+1] == '-'):
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+			pendingSpace = true
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			i += 2
+			for i < len(rs) && !(rs[i] == '*' && i+1 < len(rs) && rs[i+1] == '/') {
+				i++
+			}
+			i += 2
+			pendingSpace = true
+		case isQuote(r):
+			end := quotedEnd(rs, i)
+			write(rs[i:end])
+			i = end
+		case isPunctuation(r):
+			write(rs[i : i+1])
+			i++
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && !isQuote(rs[i]) && !isPunctuation(rs[i]) && rs[i] != '#' {
+				i++
+			}
+			write(rs[start:i])
+		}
+	}
+	return strings.TrimSuffix(buf.String(), ";")
+}
+
+// quotedEnd returns the index next to the end of the quoted string or identifier starting at i.
+// Triple-quoted strings and backslash escapes are taken into account.
+func quotedEnd(rs []rune, i int) int {
+	quote := rs[i]
+	if quote != '`' && i+2 < len(rs) && rs[i+1] == quote && rs[i+2] == quote {
+		for j := i + 3; j+2 < len(rs); j++ {
+			if rs[j] == '\\' {
+				j++
+				continue
+			}
+			if rs[j] == quote && rs[j+1] == quote && rs[j+2] == quote {
+				return j + 3
+			}
+		}
+		return len(rs)
+	}
+	for j := i + 1; j < len(rs); j++ {
+		if rs[j] == '\\' {
+			j++
+			continue
+		}
+		if rs[j] == quote {
+			return j + 1
+		}
+	}
+	return len(rs)
+}
+
+func isQuote(r rune) bool {
+	return r == '\'' || r == '"' || r == '`'
+}
+
+func isPunctuation(r rune) bool {
+	return strings.ContainsRune("(),;.=<>+-*/%|&^~!:[]{}", r)
+}
@@ -0,0 +1,29 @@
+package bqv
+
+import "testing"
+
+func TestNormalizeQuery(t *testing.T) {
+	same := [][]string{
+		{"SELECT 1 AS one", "SELECT 1 AS one\n"},
+		{"SELECT a, b\nFROM t", "SELECT a,b\r\nFROM t\r\n"},
+		{"SELECT a FROM t", "-- comment\nSELECT a # another comment\nFROM /* inline */ t;"},
+		{"SELECT COUNT(*) FROM `p.d.t`", "SELECT\n  COUNT( * )\nFROM\n  `p.d.t`"},
+	}
+	for _, pair := range same {
+		if NormalizeQuery(pair[0]) != NormalizeQuery(pair[1]) {
+			t.Errorf("%q and %q should be the same but got %q and %q", pair[0], pair[1], NormalizeQuery(pair[0]), NormalizeQuery(pair[1]))
+		}
+	}
+
+	different := [][]string{
+		{"SELECT 'a  b'", "SELECT 'a b'"},
+		{"SELECT '--' AS a", "SELECT '' AS a"},
+		{"SELECT a FROM t", "SELECT b FROM t"},
+		{"SELECT a b", "SELECT ab"},
+	}
+	for _, pair := range different {
+		if NormalizeQuery(pair[0]) == NormalizeQuery(pair[1]) {
+			t.Errorf("%q and %q should be different but both got %q", pair[0], pair[1], NormalizeQuery(pair[0]))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package bqv
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// SchemaChangeType is the kind of a SchemaChange.
+type SchemaChangeType string
+
+// The kinds of changes of the output columns of a view.
+const (
+	SchemaColumnAdded       SchemaChangeType = "column added"
+	SchemaColumnRemoved     SchemaChangeType = "column removed"
+	SchemaColumnTypeChanged SchemaChangeType = "column type changed"
+	SchemaColumnModeChanged SchemaChangeType = "column mode changed"
+)
+
+// SchemaChange is a change between the output columns of the actual view and the ones of the new query.
+type SchemaChange struct {
+	Type SchemaChangeType
+	// Column is the name of the column. The columns in a RECORD are joined with dots like "parent.child".
+	Column string
+	Old    string
+	New    string
+}
+
+// Breaking returns true if the change can break the queries which select from the view.
+// A mode change is breaking if the column becomes or stops being REPEATED, or if a NULLABLE column becomes REQUIRED.
+func (c SchemaChange) Breaking() bool {
+	switch c.Type {
+	case SchemaColumnRemoved, SchemaColumnTypeChanged:
+		return true
+	case SchemaColumnModeChanged:
+		return c.Old == "REPEATED" || c.New == "REPEATED" || (c.Old == "NULLABLE" && c.New == "REQUIRED")
+	}
+	return false
+}
+
+func (c SchemaChange) String() string {
+	prefix := ""
+	if c.Breaking() {
+		prefix = "BREAKING "
+	}
+	switch c.Type {
+	case SchemaColumnAdded:
+		return fmt.Sprintf("%s%s: %s %s", prefix, c.Type, c.Column, c.New)
+	case SchemaColumnRemoved:
+		return fmt.Sprintf("%s%s: %s %s", prefix, c.Type, c.Column, c.Old)
+	}
+	return fmt.Sprintf("%s%s: %s: %s -> %s", prefix, c.Type, c.Column, c.Old, c.New)
+}
+
+// HasBreakingChanges returns true if the diff has any schema change which can break the downstream views.
+func (d *ViewDiff) HasBreakingChanges() bool {
+	for _, change := range d.SchemaChanges {
+		if change.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffSchema returns the changes from the old schema to the new one.
+func DiffSchema(oldSchema, newSchema bigquery.Schema) []SchemaChange {
+	return diffSchema("", oldSchema, newSchema)
+}
+
+func diffSchema(prefix string, oldSchema, newSchema bigquery.Schema) []SchemaChange {
+	changes := make([]SchemaChange, 0)
+	newFields := make(map[string]*bigquery.FieldSchema)
+	for _, field := range newSchema {
+		newFields[field.Name] = field
+	}
+	oldFields := make(map[string]*bigquery.FieldSchema)
+	for _, field := range oldSchema {
+		oldFields[field.Name] = field
+	}
+
+	for _, oldField := range oldSchema {
+		name := prefix + oldField.Name
+		newField, ok := newFields[oldField.Name]
+		if !ok {
+			changes = append(changes, SchemaChange{Type: SchemaColumnRemoved, Column: name, Old: string(oldField.Type)})
+			continue
+		}
+		if oldField.Type != newField.Type {
+			changes = append(changes, SchemaChange{Type: SchemaColumnTypeChanged, Column: name, Old: string(oldField.Type), New: string(newField.Type)})
+		} else if oldField.Type == bigquery.RecordFieldType {
+			changes = append(changes, diffSchema(name+".", oldField.Schema, newField.Schema)...)
+		}
+		if oldMode, newMode := fieldMode(oldField), fieldMode(newField); oldMode != newMode {
+			changes = append(changes, SchemaChange{Type: SchemaColumnModeChanged, Column: name, Old: oldMode, New: newMode})
+		}
+	}
+	for _, newField := range newSchema {
+		if _, ok := oldFields[newField.Name]; !ok {
+			changes = append(changes, SchemaChange{Type: SchemaColumnAdded, Column: prefix + newField.Name, New: string(newField.Type)})
+		}
+	}
+	return changes
+}
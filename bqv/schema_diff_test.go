@@ -0,0 +1,66 @@
+package bqv
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+)
+
+func TestDiffSchema(t *testing.T) {
+	oldSchema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "amount", Type: bigquery.FloatFieldType},
+		{Name: "email", Type: bigquery.StringFieldType},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "zip", Type: bigquery.StringFieldType},
+		}},
+	}
+	newSchema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "amount", Type: bigquery.NumericFieldType},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+		}},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+	}
+
+	changes := DiffSchema(oldSchema, newSchema)
+	expected := []SchemaChange{
+		{Type: SchemaColumnModeChanged, Column: "id", Old: "NULLABLE", New: "REQUIRED"},
+		{Type: SchemaColumnTypeChanged, Column: "amount", Old: "FLOAT", New: "NUMERIC"},
+		{Type: SchemaColumnRemoved, Column: "email", Old: "STRING"},
+		{Type: SchemaColumnRemoved, Column: "address.zip", Old: "STRING"},
+		{Type: SchemaColumnAdded, Column: "created_at", New: "TIMESTAMP"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("%d changes were expected but got %v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("%v was expected but got %v", expected[i], changes[i])
+		}
+	}
+	if !changes[2].Breaking() || changes[4].Breaking() {
+		t.Error("The removed column should be a breaking change and the added one shouldn't")
+	}
+}
+
+func TestSchemaModeChangeBreaking(t *testing.T) {
+	cases := []struct {
+		old, new string
+		breaking bool
+	}{
+		{"NULLABLE", "REPEATED", true},
+		{"REPEATED", "NULLABLE", true},
+		{"REQUIRED", "REPEATED", true},
+		{"NULLABLE", "REQUIRED", true},
+		{"REQUIRED", "NULLABLE", false},
+	}
+	for _, c := range cases {
+		change := SchemaChange{Type: SchemaColumnModeChanged, Column: "id", Old: c.old, New: c.new}
+		if change.Breaking() != c.breaking {
+			t.Errorf("%s -> %s: breaking should be %v", c.old, c.new, c.breaking)
+		}
+	}
+}
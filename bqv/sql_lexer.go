@@ -0,0 +1,150 @@
+package bqv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlTokenKind is the kind of a token of GoogleSQL.
+type sqlTokenKind int
+
+// The kinds of tokens.
+const (
+	// sqlWord is an unquoted identifier or keyword, or a number.
+	sqlWord sqlTokenKind = iota
+	// sqlQuotedIdent is an identifier quoted with backticks.
+	sqlQuotedIdent
+	// sqlString is a string or bytes literal including the raw and triple-quoted ones.
+	sqlString
+	// sqlPunct is a single punctuation character such as ( . , * and -.
+	sqlPunct
+)
+
+// sqlToken is a token of GoogleSQL. Start and End are the byte offsets in the query.
+type sqlToken struct {
+	Kind       sqlTokenKind
+	Text       string
+	Start, End int
+}
+
+// Keyword returns the upper-cased word if the token is an unquoted word, and an empty string otherwise.
+func (t sqlToken) Keyword() string {
+	if t.Kind != sqlWord {
+		return ""
+	}
+	return strings.ToUpper(t.Text)
+}
+
+// Ident returns the identifier the token means. Backticks of a quoted identifier are removed.
+func (t sqlToken) Ident() string {
+	if t.Kind == sqlQuotedIdent {
+		return t.Text[1 : len(t.Text)-1]
+	}
+	return t.Text
+}
+
+// lexSQL splits the query into tokens skipping the whitespace and the comments.
+// It returns an error for an unterminated string, quoted identifier or comment.
+func lexSQL(q string) ([]sqlToken, error) {
+	tokens := make([]sqlToken, 0)
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '#' || strings.HasPrefix(q[i:], "--"):
+			end := strings.IndexByte(q[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(q[i:], "/*"):
+			end := strings.Index(q[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += 2 + end + 2
+		case c == '`':
+			end, err := sqlQuotedEnd(q, i, false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{Kind: sqlQuotedIdent, Text: q[i:end], Start: i, End: end})
+			i = end
+		case c == '\'' || c == '"':
+			end, err := sqlQuotedEnd(q, i, false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{Kind: sqlString, Text: q[i:end], Start: i, End: end})
+			i = end
+		case isWordByte(c):
+			start := i
+			for i < len(q) && isWordByte(q[i]) {
+				i++
+			}
+			// A string literal with prefixes such as r'...', b"..." and rb'''...'''.
+			if prefix := strings.ToLower(q[start:i]); i < len(q) && (q[i] == '\'' || q[i] == '"') && isStringPrefix(prefix) {
+				end, err := sqlQuotedEnd(q, i, strings.Contains(prefix, "r"))
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, sqlToken{Kind: sqlString, Text: q[start:end], Start: start, End: end})
+				i = end
+				continue
+			}
+			tokens = append(tokens, sqlToken{Kind: sqlWord, Text: q[start:i], Start: start, End: i})
+		default:
+			// Multi-byte characters, which appear only in literals and quoted identifiers in valid queries, become single tokens too.
+			size := 1
+			for i+size < len(q) && q[i+size]&0xC0 == 0x80 {
+				size++
+			}
+			tokens = append(tokens, sqlToken{Kind: sqlPunct, Text: q[i : i+size], Start: i, End: i + size})
+			i += size
+		}
+	}
+	return tokens, nil
+}
+
+// sqlQuotedEnd returns the offset next to the end of the quoted string or identifier starting at i.
+// Triple-quoted strings are taken into account, and backslash escapes are unless raw is true.
+func sqlQuotedEnd(q string, i int, raw bool) (int, error) {
+	quote := q[i]
+	delimiter := q[i : i+1]
+	if quote != '`' && strings.HasPrefix(q[i:], strings.Repeat(string(quote), 3)) {
+		delimiter = q[i : i+3]
+	}
+	for j := i + len(delimiter); j < len(q); j++ {
+		if q[j] == '\\' && !raw {
+			j++
+			continue
+		}
+		if strings.HasPrefix(q[j:], delimiter) {
+			return j + len(delimiter), nil
+		}
+		if q[j] == '\n' && len(delimiter) == 1 {
+			break
+		}
+	}
+	return 0, fmt.Errorf("unterminated %s at offset %d", quoteName(quote), i)
+}
+
+func quoteName(quote byte) string {
+	if quote == '`' {
+		return "quoted identifier"
+	}
+	return "string literal"
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isStringPrefix(prefix string) bool {
+	switch prefix {
+	case "r", "b", "rb", "br":
+		return true
+	}
+	return false
+}
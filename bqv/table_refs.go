@@ -0,0 +1,280 @@
+package bqv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// clauseKeywords are the words which can follow a table name without an alias.
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true, "HAVING": true, "QUALIFY": true, "WINDOW": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "ON": true, "USING": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "FOR": true, "TABLESAMPLE": true, "UNNEST": true,
+	"SELECT": true, "FROM": true, "WITH": true, "PIVOT": true, "UNPIVOT": true, "NATURAL": true,
+}
+
+// tableRefMatch is a table referenced in a query. Start and End are the byte offsets of the table name,
+// and HasAlias tells whether the table name is followed by an alias.
+type tableRefMatch struct {
+	Ref        TableRef
+	Start, End int
+	HasAlias   bool
+}
+
+// TableRefs returns the tables the query selects from in the order of their first appearance.
+// The project of a table name without one is filled with projectID.
+// The names of the CTEs, the aliases of the tables and the INFORMATION_SCHEMA views aren't regarded as tables.
+func TableRefs(q, projectID string) ([]TableRef, error) {
+	matches, err := findTableRefs(q)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]TableRef, 0)
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		ref := match.Ref
+		if ref.ProjectID == "" {
+			ref.ProjectID = projectID
+		}
+		if seen[ref.String()] {
+			continue
+		}
+		seen[ref.String()] = true
+		ret = append(ret, ref)
+	}
+	return ret, nil
+}
+
+// TableRefs renders the query of the view with the params and returns the tables it selects from.
+// See TableRefs for the details.
+func (v *ViewConfig) TableRefs(params map[string]string, projectID string) ([]TableRef, error) {
+	q, err := v.QueryWithParam(params)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := TableRefs(q, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the query of view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+	}
+	return refs, nil
+}
+
+// findTableRefs returns the tables referenced in the query and their positions.
+func findTableRefs(q string) ([]tableRefMatch, error) {
+	tokens, err := lexSQL(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &tableRefParser{
+		tokens:  tokens,
+		ctes:    cteNames(tokens),
+		aliases: make(map[string]int),
+		extract: make(map[int]bool),
+		items:   make(map[int]bool),
+		matches: make([]tableRefMatch, 0),
+	}
+	p.parse()
+	sort.Slice(p.matches, func(i, j int) bool { return p.matches[i].Start < p.matches[j].Start })
+	return p.matches, nil
+}
+
+// cteNames returns the names defined in the WITH clauses, which appear as "WITH name AS (" and ", name AS (".
+func cteNames(tokens []sqlToken) map[string]bool {
+	ret := make(map[string]bool)
+	for i := 1; i+2 < len(tokens); i++ {
+		prev := tokens[i-1]
+		if prev.Keyword() != "WITH" && prev.Keyword() != "RECURSIVE" && prev.Text != "," {
+			continue
+		}
+		if tokens[i].Kind != sqlWord && tokens[i].Kind != sqlQuotedIdent {
+			continue
+		}
+		if tokens[i+1].Keyword() == "AS" && tokens[i+2].Text == "(" {
+			ret[strings.ToLower(tokens[i].Ident())] = true
+		}
+	}
+	return ret
+}
+
+// tableRefParser finds the table names in the FROM clauses.
+// It doesn't build the syntax tree but follows FROM, JOIN and the commas between the items of the FROM clauses.
+type tableRefParser struct {
+	tokens []sqlToken
+	i      int
+	depth  int
+	ctes   map[string]bool
+	// aliases has the aliases of the items of the FROM clauses in the scope and the depths they're defined at.
+	aliases map[string]int
+	// extract has the depths of the parentheses of EXTRACT, where FROM doesn't begin a FROM clause.
+	extract map[int]bool
+	// items has the depths of the parentheses which are items of FROM clauses such as subqueries and UNNEST.
+	items   map[int]bool
+	matches []tableRefMatch
+}
+
+func (p *tableRefParser) peek(offset int) sqlToken {
+	if p.i+offset < len(p.tokens) {
+		return p.tokens[p.i+offset]
+	}
+	return sqlToken{Kind: sqlPunct, Start: -1, End: -1}
+}
+
+func (p *tableRefParser) parse() {
+	for p.i < len(p.tokens) {
+		token := p.tokens[p.i]
+		switch {
+		case token.Text == "(":
+			if p.i > 0 && p.tokens[p.i-1].Keyword() == "EXTRACT" {
+				p.extract[p.depth+1] = true
+			}
+			p.depth++
+			p.i++
+		case token.Text == ")":
+			item := p.items[p.depth]
+			delete(p.items, p.depth)
+			delete(p.extract, p.depth)
+			p.dropAliases(p.depth)
+			p.depth--
+			p.i++
+			if item {
+				p.afterItem("")
+			}
+		case token.Keyword() == "SELECT":
+			// The aliases of the previous query such as the one before UNION ALL are out of the scope.
+			p.dropAliases(p.depth)
+			p.i++
+		case token.Keyword() == "FROM" && !p.extract[p.depth], token.Keyword() == "JOIN":
+			p.i++
+			p.item()
+		default:
+			p.i++
+		}
+	}
+}
+
+// item reads an item of a FROM clause.
+func (p *tableRefParser) item() {
+	token := p.peek(0)
+	if token.Text == "(" {
+		p.items[p.depth+1] = true
+		if next := p.peek(1).Keyword(); next == "SELECT" || next == "WITH" {
+			// A subquery, whose inside is read by parse.
+			return
+		}
+		// A parenthesized join, whose first item follows the parenthesis.
+		p.depth++
+		p.i++
+		p.item()
+		return
+	}
+	if token.Keyword() == "UNNEST" {
+		p.i++
+		if p.peek(0).Text == "(" {
+			p.items[p.depth+1] = true
+		}
+		return
+	}
+	start := p.i
+	parts := p.path()
+	if len(parts) == 0 {
+		return
+	}
+	if p.peek(0).Text == "(" {
+		// A table-valued function.
+		p.items[p.depth+1] = true
+		return
+	}
+	match := tableRefMatch{Start: p.tokens[start].Start, End: p.tokens[p.i-1].End}
+	ok := p.tableRef(parts, &match.Ref)
+	match.HasAlias = p.afterItem(parts[len(parts)-1])
+	if ok {
+		p.matches = append(p.matches, match)
+	}
+}
+
+// dropAliases removes the aliases defined at the depth or deeper.
+func (p *tableRefParser) dropAliases(depth int) {
+	for alias, d := range p.aliases {
+		if d >= depth {
+			delete(p.aliases, alias)
+		}
+	}
+}
+
+// afterItem skips the alias of an item of a FROM clause and reads the next item following a comma.
+// implicit is the alias of the item without an explicit one, which is the last part of the path of a table.
+// It returns whether the item has an explicit alias.
+func (p *tableRefParser) afterItem(implicit string) bool {
+	hasAlias := false
+	if p.peek(0).Keyword() == "AS" {
+		p.i++
+	}
+	if token := p.peek(0); token.Kind == sqlQuotedIdent || (token.Kind == sqlWord && !clauseKeywords[token.Keyword()]) {
+		implicit = token.Ident()
+		hasAlias = true
+		p.i++
+	}
+	if implicit != "" {
+		p.aliases[strings.ToLower(implicit)] = p.depth
+	}
+	if p.peek(0).Text == "," {
+		p.i++
+		p.item()
+	}
+	return hasAlias
+}
+
+// path reads a dotted path such as project.dataset.table, `project.dataset.table` and `project`.dataset.`table`
+// and returns its parts. The dashes of project names such as my-project are taken into account.
+func (p *tableRefParser) path() []string {
+	parts := make([]string, 0)
+	for {
+		token := p.peek(0)
+		switch token.Kind {
+		case sqlQuotedIdent:
+			parts = append(parts, strings.Split(token.Ident(), ".")...)
+			p.i++
+		case sqlWord:
+			if clauseKeywords[token.Keyword()] {
+				return parts
+			}
+			part := token.Text
+			p.i++
+			// Dashes and the wildcard of table names adjoin the words.
+			for next := p.peek(0); next.Start == p.tokens[p.i-1].End && (next.Text == "-" || next.Text == "*" || (next.Kind == sqlWord && p.tokens[p.i-1].Text == "-")); next = p.peek(0) {
+				part += next.Text
+				p.i++
+			}
+			parts = append(parts, part)
+		default:
+			return parts
+		}
+		if p.peek(0).Text != "." {
+			return parts
+		}
+		p.i++
+	}
+}
+
+// tableRef sets the table the path means to ref. It returns false if the path doesn't mean a table
+// but a CTE, a field of an alias or an INFORMATION_SCHEMA view.
+func (p *tableRefParser) tableRef(parts []string, ref *TableRef) bool {
+	if _, ok := p.aliases[strings.ToLower(parts[0])]; ok || p.ctes[strings.ToLower(parts[0])] {
+		return false
+	}
+	for _, part := range parts {
+		if strings.ToUpper(part) == "INFORMATION_SCHEMA" {
+			return false
+		}
+	}
+	switch len(parts) {
+	case 2:
+		*ref = TableRef{DatasetID: parts[0], TableID: parts[1]}
+	case 3:
+		*ref = TableRef{ProjectID: parts[0], DatasetID: parts[1], TableID: parts[2]}
+	default:
+		return false
+	}
+	return true
+}
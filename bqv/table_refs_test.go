@@ -0,0 +1,69 @@
+package bqv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTableRefs(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected []string
+	}{
+		{"SELECT * FROM sales.orders", []string{"my-project.sales.orders"}},
+		{"SELECT * FROM `p.d.t` JOIN `p`.d.`t2` USING (id) JOIN `p`.`d`.`t3` USING (id)", []string{"p.d.t", "p.d.t2", "p.d.t3"}},
+		{"SELECT * FROM other-project.sales.orders", []string{"other-project.sales.orders"}},
+		{"SELECT * FROM sales.orders o, sales.items AS i, o.lines", []string{"my-project.sales.orders", "my-project.sales.items"}},
+		{"SELECT * FROM sales.orders, orders.lines", []string{"my-project.sales.orders"}},
+		{"-- FROM a.commented\nSELECT 'FROM a.quoted', \"\"\"JOIN a.triple\"\"\" /* FROM a.block */ FROM d.t # JOIN a.hash", []string{"my-project.d.t"}},
+		{"WITH recent AS (SELECT * FROM sales.orders), totals AS (SELECT * FROM recent) SELECT * FROM totals JOIN sales.items USING (id)", []string{"my-project.sales.orders", "my-project.sales.items"}},
+		{"SELECT EXTRACT(DAY FROM o.created_at) FROM sales.orders AS o", []string{"my-project.sales.orders"}},
+		{"SELECT * FROM (SELECT * FROM sales.orders) AS o, UNNEST(o.lines) AS l, d.t", []string{"my-project.sales.orders", "my-project.d.t"}},
+		{"SELECT * FROM region-us.INFORMATION_SCHEMA.VIEWS JOIN d.INFORMATION_SCHEMA.TABLES USING (table_name)", []string{}},
+		{"SELECT * FROM d.events_* WHERE _TABLE_SUFFIX > '2019'", []string{"my-project.d.events_*"}},
+		{"SELECT * FROM d.orders AS x UNION ALL SELECT * FROM x.y", []string{"my-project.d.orders", "my-project.x.y"}},
+		{"SELECT * FROM ML.PREDICT(MODEL d.m, TABLE d.input)", []string{}},
+		{"SELECT r'\\' FROM d.t", []string{"my-project.d.t"}},
+		{"SELECT * FROM (d.t1 JOIN d.t2 USING (k))", []string{"my-project.d.t1", "my-project.d.t2"}},
+		{"SELECT * FROM ((d.t1 AS a JOIN d.t2 USING (k)) JOIN (SELECT * FROM d.t3) USING (k)), d.t4", []string{"my-project.d.t1", "my-project.d.t2", "my-project.d.t3", "my-project.d.t4"}},
+	}
+	for _, c := range cases {
+		refs, err := TableRefs(c.query, "my-project")
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", c.query, err.Error())
+			continue
+		}
+		names := make([]string, 0)
+		for _, ref := range refs {
+			names = append(names, ref.String())
+		}
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("Unexpected references of %q: %v", c.query, names)
+		}
+	}
+}
+
+func TestFindTableRefsPositions(t *testing.T) {
+	q := "SELECT * FROM `p.d.t` t JOIN d.u ON TRUE"
+	matches, err := findTableRefs(q)
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err.Error())
+	}
+	if len(matches) != 2 {
+		t.Fatalf("Unexpected matches: %v", matches)
+	}
+	if q[matches[0].Start:matches[0].End] != "`p.d.t`" || !matches[0].HasAlias {
+		t.Errorf("Unexpected match: %v", matches[0])
+	}
+	if q[matches[1].Start:matches[1].End] != "d.u" || matches[1].HasAlias {
+		t.Errorf("Unexpected match: %v", matches[1])
+	}
+}
+
+func TestLexSQLErrors(t *testing.T) {
+	for _, q := range []string{"SELECT 'a", "SELECT `a", "SELECT /* a", "SELECT '''a''"} {
+		if _, err := lexSQL(q); err == nil {
+			t.Errorf("%q should fail", q)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package bqv
+
+import (
+	"fmt"
+	"path"
+)
+
+// Selection decides the views a command works on by the glob patterns of "dataset.view" such as "sales.*" and "*.daily_*".
+type Selection struct {
+	// Targets are the patterns of the views to work on. All the views are targets if it's empty.
+	Targets []string
+	// Excludes are the patterns of the views to skip. They win over everything else.
+	Excludes []string
+	// Changed limits the targets to these views, such as the views changed since a git ref, if it's not nil.
+	Changed []*ViewConfig
+	// WithUpstreams adds the managed views the targets select from directly or transitively.
+	WithUpstreams bool
+	// WithDownstreams adds the managed views which select from the targets directly or transitively.
+	WithDownstreams bool
+}
+
+// Validate returns an error if any of the patterns is malformed.
+func (s Selection) Validate() error {
+	for _, pattern := range append(append([]string{}, s.Targets...), s.Excludes...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern(%s): %s", pattern, err.Error())
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the view is one of the targets and isn't excluded.
+func (s Selection) Matches(datasetName, viewName string) bool {
+	return (len(s.Targets) == 0 || matchesAny(s.Targets, datasetName, viewName)) && !matchesAny(s.Excludes, datasetName, viewName)
+}
+
+// Select returns the selected views in the order of configs.
+// graph is used to add the upstream and the downstream views, and can be nil if neither of them is needed.
+func (s Selection) Select(configs []*ViewConfig, graph *DependencyGraph) []*ViewConfig {
+	var changed map[*ViewConfig]bool
+	if s.Changed != nil {
+		changed = make(map[*ViewConfig]bool)
+		for _, config := range s.Changed {
+			changed[config] = true
+		}
+	}
+	selected := make(map[*ViewConfig]bool)
+	for _, config := range configs {
+		if len(s.Targets) > 0 && !matchesAny(s.Targets, config.DatasetName, config.ViewName) {
+			continue
+		}
+		if changed != nil && !changed[config] {
+			continue
+		}
+		selected[config] = true
+		if graph == nil {
+			continue
+		}
+		if s.WithUpstreams {
+			for _, upstream := range graph.AllUpstreams(config) {
+				selected[upstream] = true
+			}
+		}
+		if s.WithDownstreams {
+			for _, downstream := range graph.AllDownstreams(config) {
+				selected[downstream] = true
+			}
+		}
+	}
+
+	ret := make([]*ViewConfig, 0, len(selected))
+	for _, config := range configs {
+		if selected[config] && !matchesAny(s.Excludes, config.DatasetName, config.ViewName) {
+			ret = append(ret, config)
+		}
+	}
+	return ret
+}
+
+func matchesAny(patterns []string, datasetName, viewName string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, datasetName+"."+viewName); ok {
+			return true
+		}
+	}
+	return false
+}
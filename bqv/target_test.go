@@ -0,0 +1,53 @@
+package bqv
+
+import "testing"
+
+func TestSelectionMatches(t *testing.T) {
+	cases := []struct {
+		selection Selection
+		dataset   string
+		view      string
+		expected  bool
+	}{
+		{Selection{}, "sales", "daily", true},
+		{Selection{Targets: []string{"sales.*"}}, "sales", "daily", true},
+		{Selection{Targets: []string{"sales.*"}}, "sales_eu", "daily", false},
+		{Selection{Targets: []string{"*.daily_*"}}, "sales", "daily_orders", true},
+		{Selection{Targets: []string{"*.daily_*"}}, "sales", "weekly_orders", false},
+		{Selection{Targets: []string{"users.*", "sales.daily"}}, "sales", "daily", true},
+		{Selection{Excludes: []string{"sales.d*"}}, "sales", "daily", false},
+		{Selection{Targets: []string{"sales.*"}, Excludes: []string{"*.weekly"}}, "sales", "daily", true},
+	}
+	for _, c := range cases {
+		if actual := c.selection.Matches(c.dataset, c.view); actual != c.expected {
+			t.Errorf("%v.Matches(%s, %s) should be %v", c.selection, c.dataset, c.view, c.expected)
+		}
+	}
+
+	if err := (Selection{Excludes: []string{"sales.[a"}}).Validate(); err == nil {
+		t.Error("the malformed pattern should be rejected")
+	}
+}
+
+func TestSelectionSelect(t *testing.T) {
+	g, configs := testGraph(t)
+	list := []*ViewConfig{configs["sales.orders"], configs["sales.daily"], configs["sales.weekly"], configs["report.latest"]}
+
+	cases := []struct {
+		selection Selection
+		expected  string
+	}{
+		{Selection{Targets: []string{"sales.daily"}}, "sales.daily"},
+		{Selection{Targets: []string{"sales.weekly"}, WithUpstreams: true}, "sales.orders,sales.daily,sales.weekly"},
+		{Selection{Targets: []string{"sales.daily"}, WithDownstreams: true}, "sales.daily,sales.weekly,report.latest"},
+		{Selection{Targets: []string{"sales.daily"}, WithDownstreams: true, Excludes: []string{"report.*"}}, "sales.daily,sales.weekly"},
+		{Selection{Excludes: []string{"sales.*"}}, "report.latest"},
+		{Selection{Changed: []*ViewConfig{configs["sales.daily"]}, WithDownstreams: true}, "sales.daily,sales.weekly,report.latest"},
+		{Selection{Targets: []string{"report.*"}, Changed: []*ViewConfig{configs["sales.daily"]}}, ""},
+	}
+	for _, c := range cases {
+		if names := viewNames(c.selection.Select(list, g)); names != c.expected {
+			t.Errorf("%v should select %s but got %s", c.selection, c.expected, names)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package bqv
+
+import (
+	"bytes"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/sirupsen/logrus"
+)
+
+// renderedTemplate is the text rendered from a template, which knows where each part of it comes from in the template.
+type renderedTemplate struct {
+	Text     string
+	source   string
+	segments []templateSegment
+}
+
+// templateSegment is a part of the rendered text. The text of a text node maps to the source byte for byte,
+// and the output of an action maps to the start of the action.
+type templateSegment struct {
+	rendered, source, length int
+	text                     bool
+}
+
+// renderTemplate renders the template with the params in the same way as executeTemplate
+// and maps the rendered text back to the template.
+func renderTemplate(name, text string, params map[string]string) (*renderedTemplate, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		logrus.Errorf("Failed to parse template: %s", err.Error())
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, params); err != nil {
+		logrus.Errorf("Failed to execute template: %s", err.Error())
+		return nil, err
+	}
+	ret := &renderedTemplate{Text: buf.String(), source: text}
+	if t.Tree != nil {
+		ret.segments = mapTemplate(name, t.Tree.Root.Nodes, params, ret.Text)
+	}
+	return ret, nil
+}
+
+// mapTemplate renders the top-level nodes one by one and returns the segments of the rendered text.
+// It returns nil if they don't add up to the whole text, as when an action uses a variable declared by another one.
+func mapTemplate(name string, nodes []parse.Node, params map[string]string, rendered string) []templateSegment {
+	segments := make([]templateSegment, 0, len(nodes))
+	offset := 0
+	for _, node := range nodes {
+		segment := templateSegment{rendered: offset, source: int(node.Position())}
+		if text, ok := node.(*parse.TextNode); ok {
+			segment.length = len(text.Text)
+			segment.text = true
+		} else {
+			tree := &parse.Tree{Name: name, Root: &parse.ListNode{NodeType: parse.NodeList, Nodes: []parse.Node{node}}}
+			t, err := template.New(name).AddParseTree(name, tree)
+			if err != nil {
+				return nil
+			}
+			var buf bytes.Buffer
+			if err = t.Execute(&buf, params); err != nil {
+				return nil
+			}
+			segment.length = buf.Len()
+		}
+		segments = append(segments, segment)
+		offset += segment.length
+	}
+	if offset != len(rendered) {
+		return nil
+	}
+	return segments
+}
+
+// position returns the 1-based line and column in the template of the offset in the rendered text.
+func (r *renderedTemplate) position(offset int64) (int, int) {
+	if r.segments == nil {
+		return position([]byte(r.Text), offset)
+	}
+	source := int64(len(r.source))
+	for _, segment := range r.segments {
+		if offset >= int64(segment.rendered+segment.length) {
+			continue
+		}
+		source = int64(segment.source)
+		if segment.text {
+			source += offset - int64(segment.rendered)
+		}
+		break
+	}
+	return position([]byte(r.source), source)
+}
@@ -0,0 +1,175 @@
+package bqv
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// maxViewQueryLength is the maximum number of characters in the query of a view BigQuery accepts.
+const maxViewQueryLength = 256 * 1024
+
+// maxNameLength is the maximum number of characters in the name of a dataset or a view BigQuery accepts.
+const maxNameLength = 1024
+
+var (
+	datasetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	viewNamePattern    = regexp.MustCompile(`^[\p{L}\p{M}\p{N}\p{Pc}\p{Pd}\p{Zs}]+$`)
+	labelKeyPattern    = regexp.MustCompile(`^[\p{Ll}\p{Lo}][\p{Ll}\p{Lo}\p{N}_-]{0,62}$`)
+	labelValuePattern  = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}_-]{0,63}$`)
+)
+
+// Problem is a problem found in the files which define the views.
+// Line and Column are the position of the problem in the file, or zero if it's unknown.
+type Problem struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", p.Path, p.Line, p.Column, p.Message)
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Validate checks all the views defined in the dir directory without accessing BigQuery, and returns every problem found.
+// It renders the templates with the params, parses meta.json strictly and checks the names and the limits of BigQuery.
+func Validate(dir string, params map[string]string) []Problem {
+	return ValidateFS(os.DirFS(dir), dir, params)
+}
+
+// ValidateFS checks all the views defined in the filesystem fsys such as the tree at a git revision in the same way as Validate.
+// name is the path of the filesystem shown in the problems.
+func ValidateFS(fsys fs.FS, name string, params map[string]string) []Problem {
+	tree := viewTree{fsys: fsys, name: name}
+	problems := make([]Problem, 0)
+	datasets, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return append(problems, Problem{Path: name, Message: err.Error()})
+	}
+
+	for _, dataset := range datasets {
+		if !dataset.IsDir() {
+			continue
+		}
+		datasetDir := dataset.Name()
+		views, err := fs.ReadDir(fsys, datasetDir)
+		if err != nil {
+			problems = append(problems, Problem{Path: tree.path(datasetDir), Message: err.Error()})
+			continue
+		}
+
+		hasView := false
+		for _, view := range views {
+			if !view.IsDir() {
+				continue
+			}
+			viewDir := path.Join(datasetDir, view.Name())
+			if _, err := fs.Stat(fsys, path.Join(viewDir, "query.sql")); os.IsNotExist(err) {
+				continue
+			}
+			hasView = true
+			if !viewNamePattern.MatchString(view.Name()) || utf8.RuneCountInString(view.Name()) > maxNameLength {
+				problems = append(problems, Problem{Path: tree.path(viewDir), Message: fmt.Sprintf("invalid view name(%s)", view.Name())})
+			}
+			problems = append(problems, validateView(tree, viewDir, params)...)
+		}
+		if hasView {
+			problems = append(problems, validateDatasetFile(tree, datasetDir)...)
+		}
+		if hasView && (!datasetNamePattern.MatchString(dataset.Name()) || len(dataset.Name()) > maxNameLength) {
+			problems = append(problems, Problem{Path: tree.path(datasetDir), Message: fmt.Sprintf("invalid dataset name(%s): it must consist of letters, numbers and underscores", dataset.Name())})
+		}
+	}
+	return problems
+}
+
+func validateView(tree viewTree, viewDir string, params map[string]string) []Problem {
+	problems := make([]Problem, 0)
+
+	queryFileName := tree.path(path.Join(viewDir, "query.sql"))
+	query, sources, err := readViewFiles(tree, viewDir)
+	if err != nil {
+		return append(problems, loadProblems(tree.path(viewDir), err)...)
+	}
+	if q, err := executeTemplate("q", *query, params); err != nil {
+		problems = append(problems, Problem{Path: queryFileName, Message: err.Error()})
+	} else if n := utf8.RuneCountInString(q); n > maxViewQueryLength {
+		problems = append(problems, Problem{Path: queryFileName, Message: fmt.Sprintf("query has %d characters which is more than the limit %d", n, maxViewQueryLength)})
+	}
+
+	if len(sources) == 0 {
+		return problems
+	}
+	md, err := renderMetadata(sources, params)
+	if err != nil {
+		return append(problems, loadProblems(tree.path(viewDir), err)...)
+	}
+	// The problems of the merged metadata are reported at the only source or the view dir.
+	metadataPath := tree.path(viewDir)
+	if len(sources) == 1 {
+		metadataPath = sources[0].Path
+	}
+	for _, message := range validateMetadata(md) {
+		problems = append(problems, Problem{Path: metadataPath, Message: message})
+	}
+	return problems
+}
+
+// validateDatasetFile validates dataset.json in the dataset directory if it exists.
+func validateDatasetFile(tree viewTree, datasetDir string) []Problem {
+	_, err := readDatasetConfig(tree, datasetDir)
+	if err != nil {
+		return loadProblems(tree.path(datasetDir), err)
+	}
+	return nil
+}
+
+// loadProblems converts the error which occurred while loading the files in dir into Problems.
+func loadProblems(dir string, err error) []Problem {
+	loadErr := new(LoadError)
+	loadErr.add(dir, err)
+	problems := make([]Problem, 0, len(loadErr.Errors))
+	for _, fileErr := range loadErr.Errors {
+		problems = append(problems, Problem{Path: fileErr.Path, Line: fileErr.Line, Column: fileErr.Column, Message: fileErr.Err.Error()})
+	}
+	return problems
+}
+
+// validateMetadata returns the problems of the values in the metadata.
+func validateMetadata(md *Metadata) []string {
+	messages := make([]string, 0)
+	if _, err := md.Expiration(time.Now()); err != nil {
+		messages = append(messages, err.Error())
+	}
+	keys := make([]string, 0, len(md.Labels))
+	for key := range md.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := md.Labels[key]
+		if !labelKeyPattern.MatchString(key) {
+			messages = append(messages, fmt.Sprintf("invalid label key(%s)", key))
+		}
+		if !labelValuePattern.MatchString(value) {
+			messages = append(messages, fmt.Sprintf("invalid value of label(%s): %s", key, value))
+		}
+	}
+	columns := make(map[string]bool)
+	for _, column := range md.Schema {
+		if columns[column.Name] {
+			messages = append(messages, fmt.Sprintf("column(%s) is documented more than once", column.Name))
+		}
+		columns[column.Name] = true
+	}
+	return messages
+}
@@ -0,0 +1,54 @@
+package bqv
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %s", err.Error())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bqv")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "sales", "ok", "query.sql"), "SELECT '{{.env}}' AS env")
+	writeTestFile(t, filepath.Join(dir, "sales", "ok", "meta.json"), `{"description": "{{.env}}", "labels": {"env": "{{.env}}"}}`)
+	writeTestFile(t, filepath.Join(dir, "sales", "typo", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "typo", "meta.json"), `{"descripton": "typo"}`)
+	writeTestFile(t, filepath.Join(dir, "sales", "broken", "query.sql"), "SELECT {{.env")
+	writeTestFile(t, filepath.Join(dir, "sales-eu", "view", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "labels", "query.sql"), "SELECT 1")
+	writeTestFile(t, filepath.Join(dir, "sales", "labels", "meta.json"), `{"labels": {"Env": "prod"}}`)
+
+	problems := Validate(dir, map[string]string{"env": "prod"})
+	expected := []string{
+		filepath.Join(dir, "sales", "broken", "query.sql"),
+		filepath.Join(dir, "sales", "labels", "meta.json"),
+		filepath.Join(dir, "sales", "typo", "meta.json"),
+		filepath.Join(dir, "sales-eu"),
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("%d problems were expected but got %v", len(expected), problems)
+	}
+	for i := range expected {
+		if problems[i].Path != expected[i] {
+			t.Errorf("A problem in %s was expected but got %s", expected[i], problems[i])
+		}
+	}
+	if !strings.Contains(problems[2].Message, "descripton") {
+		t.Errorf("The unknown field should be reported but got %s", problems[2].Message)
+	}
+}
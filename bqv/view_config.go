@@ -3,14 +3,13 @@ package bqv
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
-	"sort"
-	"strings"
 	"text/template"
+	"time"
 
 	"google.golang.org/api/googleapi"
 
@@ -21,40 +20,92 @@ import (
 
 // ViewConfig is...
 type ViewConfig struct {
-	Query            string
-	ViewName         string
-	DatasetName      string
-	MetadataFromFile struct {
-		Description string `json:"description"`
-		Schema      []struct {
-			Name        string `json:"name"`
-			Description string `json:"description,omitempty"`
-		} `json:"schema"`
-		Labels map[string]string `json:"labels,omitempty"`
-	}
+	Query       string
+	ViewName    string
+	DatasetName string
+	// MetadataFromFile is the metadata read from the files without template actions.
+	// It's empty if the files have template actions, which MetadataWithParam renders.
+	MetadataFromFile Metadata
+	// MetadataSources are the templates of the metadata which are rendered with the params in the same way as Query.
+	MetadataSources []MetadataSource
+	// Dataset is the metadata in dataset.json of the dataset. It's nil if the dataset has no dataset.json.
+	Dataset *DatasetConfig
+	Options Options
+}
+
+// Options changes how a ViewConfig compares itself with the actual view and applies itself.
+type Options struct {
+	// LabelMode decides which labels of the view bqv touches. LabelModeReplace is used if it's empty.
+	LabelMode LabelMode
+	// RawQueryComparison makes the queries compared byte for byte.
+	// They're compared ignoring insignificant whitespace and comments by default.
+	RawQueryComparison bool
+	// Force makes Apply deploy the view even if it breaks its contract.
+	Force bool
+	// MaxBytesProcessed is the limit of the bytes a full SELECT * on the view may process. No limit if it's 0.
+	MaxBytesProcessed int64
+	// MaxCostGrowthPercent is the limit of how much the bytes processed may grow in percent. No limit if it's 0.
+	MaxCostGrowthPercent float64
+	// ValidateAll makes DryRun validate the queries of all the views even if they haven't changed.
+	ValidateAll bool
+	// StrictDocs makes the mismatches between the documented columns and the actual ones errors instead of warnings.
+	StrictDocs bool
+	// Lineage records the tables BigQuery reports in the dry runs if it's not nil.
+	Lineage *LineageCache
 }
 
 // ViewDiff is...
 type ViewDiff struct {
-	ViewName           string
-	DatasetName        string
-	OldViewQuery       string
-	NewViewQuery       string
-	MetadataUpdateFlag bool
+	ViewName     string
+	DatasetName  string
+	OldViewQuery string
+	NewViewQuery string
+	// NewView is true if the view doesn't exist yet.
+	NewView bool
+	// QueryChanged is true if the queries are different in the way Options.RawQueryComparison decides.
+	QueryChanged bool
+	// MetadataChanges are the changes of the metadata. It's empty when the view doesn't exist yet.
+	MetadataChanges []MetadataChange
+	// ForeignLabels are the keys of the labels set by other systems, which bqv leaves alone.
+	ForeignLabels []string
+	// OldSchema is the schema of the actual view. It's nil when the view doesn't exist yet.
+	OldSchema bigquery.Schema
+	// NewSchema is the schema the new query produces. It's nil when DryRunError isn't nil.
+	NewSchema bigquery.Schema
+	// SchemaChanges are the changes of the output columns. It's empty when the view doesn't exist yet.
+	SchemaChanges []SchemaChange
+	// Cost is the estimated cost of the new query. It's nil unless the query changed.
+	Cost *CostEstimate
+	// DryRunError is the error of the dry run which got the schema of the new query.
+	DryRunError error
+	// ContractViolations are the differences between the contract and the schema the new query produces.
+	ContractViolations []ContractViolation
+	// DocIssues are the mismatches between the columns documented in meta.json and the schema the new query produces.
+	DocIssues []DocIssue
 }
 
 // Apply creates the view or updates it when it existed.
 // Apply returns (true, nil) if the view changed and (false ,nil) if the view didn't change
 func (v *ViewConfig) Apply(ctx context.Context, client *bigquery.Client, params map[string]string) (bool, error) {
+	diff, err := v.Diff(ctx, client, params)
+	if err != nil {
+		logrus.Errorf("Failed to get diff of view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+		return false, err
+	}
+	return v.ApplyDiff(ctx, client, params, diff)
+}
+
+// ApplyDiff works as Apply with the diff Diff has returned, which saves the dry runs and the metadata calls
+// when the caller has already compared the view. The diff has to be computed after the managed views the view selects from
+// have been applied. DependencyGraph.ApplyViews takes care of it.
+func (v *ViewConfig) ApplyDiff(ctx context.Context, client *bigquery.Client, params map[string]string, diff *ViewDiff) (bool, error) {
 	dataset := client.Dataset(v.DatasetName)
 
 	// check if the dataset exists.
 	_, err := dataset.Metadata(ctx)
 	if err != nil && hasStatusCode(err, http.StatusNotFound) {
 		logrus.Infof("Dataset(%s) was not found. creating it...", dataset.DatasetID)
-		err = dataset.Create(ctx, &bigquery.DatasetMetadata{
-			Name: dataset.DatasetID,
-		})
+		err = dataset.Create(ctx, v.Dataset.datasetMetadata(dataset.DatasetID))
 		if err != nil {
 			logrus.Errorf("Failed to create dataset: %s", err.Error())
 			return false, err
@@ -67,22 +118,53 @@ func (v *ViewConfig) Apply(ctx context.Context, client *bigquery.Client, params
 		logrus.Errorf("Failed to execute template: %s", err.Error())
 		return false, err
 	}
+	md, err := v.MetadataWithParam(params)
+	if err != nil {
+		logrus.Errorf("Failed to get metadata: %s", err.Error())
+		return false, err
+	}
+	expiration, err := md.Expiration(time.Now())
+	if err != nil {
+		logrus.Errorf("Failed to get expiration time: %s", err.Error())
+		return false, err
+	}
 
-	view := client.Dataset(v.DatasetName).Table(v.ViewName)
-	m, err := view.Metadata(ctx)
-
-	if err == nil { // skip updating view if no change
-		diff, err := v.Diff(ctx, client, params)
-		if err != nil {
-			logrus.Errorf("Failed to get diff of view(%s.%s): %s", diff.DatasetName, diff.ViewName, err.Error())
+	if diff == nil { // skip updating view if no change
+		logrus.Infof("Skipping View(%s.%s). It exists and its query hasn't changed.", v.DatasetName, v.ViewName)
+		return false, nil
+	}
+	if diff.DryRunError != nil {
+		logrus.Errorf("Refused to apply view(%s.%s) whose query failed in dry run: %s", v.DatasetName, v.ViewName, diff.DryRunError.Error())
+		return false, diff.DryRunError
+	}
+	if len(diff.ContractViolations) > 0 {
+		contractErr := &ContractError{DatasetName: v.DatasetName, ViewName: v.ViewName, Violations: diff.ContractViolations}
+		if !v.Options.Force {
+			logrus.Errorf("Refused to apply: %s", contractErr.Error())
+			return false, contractErr
 		}
-		if diff == nil {
-			logrus.Infof("Skipping View(%s.%s). It exists and its query hasn't changed.", view.DatasetID, view.TableID)
-			return false, nil
+		logrus.Warnf("Applying anyway: %s", contractErr.Error())
+	}
+	if err = v.reportDocIssues(diff.DocIssues); err != nil {
+		return false, err
+	}
+	if err = v.CheckCost(diff.Cost); err != nil {
+		if !v.Options.Force {
+			logrus.Errorf("Refused to apply: %s", err.Error())
+			return false, err
 		}
-	} else { // create view if not exists
+		logrus.Warnf("Applying anyway: %s", err.Error())
+	}
+	if err = checkPolicyTagColumns(diff.NewSchema, md.ColumnPolicyTags()); err != nil {
+		logrus.Errorf("Refused to apply view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+		return false, err
+	}
+
+	view := client.Dataset(v.DatasetName).Table(v.ViewName)
+	m, err := view.Metadata(ctx)
+	if err != nil { // create view if not exists
 		err = view.Create(ctx, &bigquery.TableMetadata{
-			Name:           v.ViewName,
+			Name:           md.FriendlyNameOr(v.ViewName),
 			ViewQuery:      q,
 			UseStandardSQL: true,
 		})
@@ -100,30 +182,49 @@ func (v *ViewConfig) Apply(ctx context.Context, client *bigquery.Client, params
 	logrus.Infof("Creating or Updating view(%s.%s) ...", view.DatasetID, view.TableID)
 
 	// parse metadata from file
-	if &v.MetadataFromFile != nil {
+	if md != nil {
 		for _, field := range m.Schema {
-			for _, newValue := range v.MetadataFromFile.Schema {
+			for _, newValue := range md.Schema {
 				if field.Name == newValue.Name {
 					field.Description = newValue.Description
 				}
 			}
 		}
-		m.Description = v.MetadataFromFile.Description
+		m.Description = md.Description
 	}
 
 	// update view
 	tm := bigquery.TableMetadataToUpdate{
-		Name:         v.ViewName,
-		ViewQuery:    q,
+		Name:         md.FriendlyNameOr(v.ViewName),
 		UseLegacySQL: false,
 		Description:  m.Description,
 		Schema:       m.Schema,
 	}
+	// Leave the query as it is if it differs only in formatting, which keeps the cached schema of the view.
+	if diff.QueryChanged {
+		tm.ViewQuery = q
+	}
+	if !expiration.IsZero() {
+		tm.ExpirationTime = expiration
+	} else if !m.ExpirationTime.IsZero() {
+		tm.ExpirationTime = bigquery.NeverExpire
+	}
+	labels, foreign, err := desiredLabels(m.Labels, md.Labels, v.Options.LabelMode)
+	if err != nil {
+		logrus.Errorf("Failed to decide labels: %s", err.Error())
+		return false, err
+	}
+	for _, key := range foreign {
+		logrus.Debugf("Leave label (%s:%s) alone ...", key, m.Labels[key])
+	}
 	for key, value := range m.Labels {
+		if _, ok := labels[key]; ok {
+			continue
+		}
 		tm.DeleteLabel(key)
 		logrus.Debugf("Delete labels (%s:%s) ...", key, value)
 	}
-	for key, value := range v.MetadataFromFile.Labels {
+	for key, value := range labels {
 		tm.SetLabel(key, value)
 		logrus.Debugf("Set labels (%s:%s) ...", key, value)
 	}
@@ -133,34 +234,126 @@ func (v *ViewConfig) Apply(ctx context.Context, client *bigquery.Client, params
 		return false, err
 	}
 
+	// update policy tags, which are left alone if meta.json declares none.
+	if newTags := md.ColumnPolicyTags(); len(newTags) > 0 {
+		currentTags, err := getColumnPolicyTags(ctx, client, view)
+		if err != nil {
+			logrus.Errorf("Failed to get policy tags: %s", err.Error())
+			return false, err
+		}
+		if !equalPolicyTags(currentTags, newTags) {
+			if err = setColumnPolicyTags(ctx, client, view, newTags); err != nil {
+				logrus.Errorf("Failed to update policy tags: %s", err.Error())
+				return false, err
+			}
+		}
+	}
+
 	return true, nil
 }
 
 // DryRun tests Query is valid by executing the query in dry-run mode.
 // DryRun returns true if the view might get created or updated when you call Apply and false if not.
 func (v *ViewConfig) DryRun(ctx context.Context, client *bigquery.Client, params map[string]string) (bool, error) {
-	m, err := v.getViewMetaDataIfExists(ctx, client)
+	q, err := v.QueryWithParam(params)
 	if err != nil {
-		logrus.Errorf("Failed to get the metadata of this table: %s", err.Error())
+		logrus.Errorf("Failed to create query: %s", err.Error())
 		return false, err
 	}
+	return v.dryRun(ctx, client, params, q)
+}
+
+// dryRun is DryRun which sends dryRunQuery to BigQuery instead of the query of the view.
+func (v *ViewConfig) dryRun(ctx context.Context, client *bigquery.Client, params map[string]string, dryRunQuery string) (bool, error) {
 	q, err := v.QueryWithParam(params)
 	if err != nil {
 		logrus.Errorf("Failed to create query: %s", err.Error())
 		return false, err
 	}
-	if strings.Compare(m.ViewQuery, q) == 0 {
+	md, err := v.MetadataWithParam(params)
+	if err != nil {
+		logrus.Errorf("Failed to get metadata: %s", err.Error())
+		return false, err
+	}
+	diff, err := v.diffWithActualView(ctx, client, q, md)
+	if err != nil {
+		logrus.Errorf("Failed to get diff of view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+		return false, err
+	}
+
+	switch {
+	case diff == nil:
 		logrus.Infof("View(%s.%s) won't change", v.DatasetName, v.ViewName)
-		return false, nil
+	case diff.NewView:
+		logrus.Infof("View(%s.%s) will be created", v.DatasetName, v.ViewName)
+	case diff.QueryChanged:
+		logrus.Infof("Query of view(%s.%s) will change", v.DatasetName, v.ViewName)
+	}
+	if diff != nil {
+		for _, change := range diff.MetadataChanges {
+			logrus.Infof("View(%s.%s) %s", v.DatasetName, v.ViewName, change)
+		}
+	}
+	changed := diff != nil
+	if (diff == nil || !diff.QueryChanged) && !v.Options.ValidateAll {
+		v.ensureLineage(ctx, client, q)
+		return changed, nil
 	}
 
+	stats, err := runDryRun(ctx, client, dryRunQuery)
+	if err != nil {
+		return changed, err
+	}
+	// The tables of the inlined queries aren't the ones the view will reference.
+	if dryRunQuery == q {
+		v.recordLineage(q, stats)
+	}
+
+	if violations := CheckContract(stats.Schema, md.Contract); len(violations) > 0 {
+		contractErr := &ContractError{DatasetName: v.DatasetName, ViewName: v.ViewName, Violations: violations}
+		logrus.Errorf("Contract check failed: %s", contractErr.Error())
+		return changed, contractErr
+	}
+	if err = v.reportDocIssues(CheckColumnDocs(stats.Schema, md.Schema)); err != nil {
+		return changed, err
+	}
+
+	cost := v.estimateCost(ctx, client, stats, diff != nil && !diff.NewView)
+	logrus.Infof("View(%s.%s) %s", v.DatasetName, v.ViewName, cost)
+	logrus.Infof("View(%s.%s) reads %s", v.DatasetName, v.ViewName, formatTableRefs(cost.ReferencedTables))
+	if err = v.CheckCost(cost); err != nil {
+		logrus.Errorf("Cost check failed: %s", err.Error())
+		return changed, err
+	}
+
+	logrus.Infof("View(%s.%s) seems OK", v.DatasetName, v.ViewName)
+	return changed, nil
+}
+
+// reportDocIssues logs the documentation issues as warnings,
+// or returns them as an error if Options.StrictDocs is true.
+func (v *ViewConfig) reportDocIssues(issues []DocIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	docErr := &DocError{DatasetName: v.DatasetName, ViewName: v.ViewName, Issues: issues}
+	if v.Options.StrictDocs {
+		logrus.Errorf("%s", docErr.Error())
+		return docErr
+	}
+	logrus.Warnf("%s", docErr.Error())
+	return nil
+}
+
+// runDryRun executes the query in dry-run mode and returns its statistics.
+func runDryRun(ctx context.Context, client *bigquery.Client, q string) (*bigquery.QueryStatistics, error) {
 	query := client.Query(q)
 	query.DryRun = true
 	job, err := query.Run(ctx)
 	if err != nil {
 		logrus.Errorf("Failed to run the query: %s", err.Error())
 		logrus.Errorf("query: %s", q)
-		return false, err
+		return nil, err
 	}
 
 	// https://github.com/GoogleCloudPlatform/golang-samples/blob/master/bigquery/snippets/snippet.go#L1106
@@ -168,11 +361,14 @@ func (v *ViewConfig) DryRun(ctx context.Context, client *bigquery.Client, params
 	jobStatus := job.LastStatus()
 	if jobStatus.Err() != nil {
 		logrus.Errorf("Dry run failed: %s", jobStatus.Err().Error())
-		return true, jobStatus.Err()
+		return nil, jobStatus.Err()
 	}
 
-	logrus.Infof("View(%s.%s) seems OK", v.DatasetName, v.ViewName)
-	return true, nil
+	stats, ok := jobStatus.Statistics.Details.(*bigquery.QueryStatistics)
+	if !ok {
+		return &bigquery.QueryStatistics{}, nil
+	}
+	return stats, nil
 }
 
 func (v *ViewConfig) getViewMetaDataIfExists(ctx context.Context, client *bigquery.Client) (*bigquery.TableMetadata, error) {
@@ -182,13 +378,20 @@ func (v *ViewConfig) getViewMetaDataIfExists(ctx context.Context, client *bigque
 		logrus.Debugf("Dataset(%s) didn't exist.", v.DatasetName)
 		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 	view := client.Dataset(v.DatasetName).Table(v.ViewName)
 	m, err := view.Metadata(ctx)
-	if err == nil {
-		logrus.Debugf("View(%s.%s) was found", v.DatasetName, v.ViewName)
-		return m, nil
+	if err != nil && hasStatusCode(err, http.StatusNotFound) {
+		logrus.Debugf("View(%s.%s) didn't exist.", v.DatasetName, v.ViewName)
+		return nil, nil
 	}
-	return nil, nil
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("View(%s.%s) was found", v.DatasetName, v.ViewName)
+	return m, nil
 }
 
 // DeleteIfExist deletes the view if it exists.
@@ -211,11 +414,38 @@ func (v *ViewConfig) DeleteIfExist(ctx context.Context, client *bigquery.Client)
 	return false, nil
 }
 
+// queryChanged returns true if the query of the actual view and the new one are different.
+func (v *ViewConfig) queryChanged(old, new string) bool {
+	if v.Options.RawQueryComparison {
+		return old != new
+	}
+	return NormalizeQuery(old) != NormalizeQuery(new)
+}
+
 // QueryWithParam returns the SQL made of the template Query and the given params.
 func (v *ViewConfig) QueryWithParam(params map[string]string) (string, error) {
-	t, err := template.New("q").Parse(v.Query)
+	return executeTemplate("q", v.Query, params)
+}
+
+// MetadataWithParam returns the Metadata made of the templates MetadataSources and the given params.
+// MetadataWithParam returns MetadataFromFile if MetadataSources is empty.
+func (v *ViewConfig) MetadataWithParam(params map[string]string) (*Metadata, error) {
+	if len(v.MetadataSources) == 0 {
+		md := v.MetadataFromFile
+		return &md, nil
+	}
+	md, err := renderMetadata(v.MetadataSources, params)
+	if err != nil {
+		logrus.Errorf("Invalid metadata of view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+		return nil, err
+	}
+	return md, nil
+}
+
+func executeTemplate(name, text string, params map[string]string) (string, error) {
+	t, err := template.New(name).Parse(text)
 	if err != nil {
-		logrus.Errorf("Failed to parse query: %s", err.Error())
+		logrus.Errorf("Failed to parse template: %s", err.Error())
 		return "", err
 	}
 	var buf bytes.Buffer
@@ -233,15 +463,85 @@ func (v *ViewConfig) Diff(ctx context.Context, client *bigquery.Client, params m
 		logrus.Errorf("Failed to get query: %s", err.Error())
 		return nil, err
 	}
+	md, err := v.MetadataWithParam(params)
+	if err != nil {
+		logrus.Errorf("Failed to get metadata: %s", err.Error())
+		return nil, err
+	}
 
+	diff, err := v.diffWithActualView(ctx, client, q, md)
+	if err != nil {
+		return nil, err
+	}
+	if diff == nil {
+		v.ensureLineage(ctx, client, q)
+		return nil, nil
+	}
+
+	// The schema of the new query is the one of the actual view unless the query changed.
+	diff.NewSchema = diff.OldSchema
+	if !diff.QueryChanged {
+		v.ensureLineage(ctx, client, q)
+	} else {
+		stats, err := runDryRun(ctx, client, q)
+		if err != nil {
+			logrus.Warnf("Failed to get the schema of view(%s.%s) by dry run: %s", v.DatasetName, v.ViewName, err.Error())
+			diff.DryRunError = err
+			return diff, nil
+		}
+		v.recordLineage(q, stats)
+		diff.NewSchema = stats.Schema
+		if diff.OldSchema != nil {
+			diff.SchemaChanges = DiffSchema(diff.OldSchema, diff.NewSchema)
+		}
+		diff.Cost = v.estimateCost(ctx, client, stats, !diff.NewView)
+	}
+	diff.ContractViolations = CheckContract(diff.NewSchema, md.Contract)
+	diff.DocIssues = CheckColumnDocs(diff.NewSchema, md.Schema)
+	return diff, nil
+}
+
+// CheckDocs compares the columns documented in meta.json with the columns of the view.
+// The columns are taken from the actual view if its query hasn't changed and from a dry run of the new query if it has.
+func (v *ViewConfig) CheckDocs(ctx context.Context, client *bigquery.Client, params map[string]string) ([]DocIssue, error) {
+	q, err := v.QueryWithParam(params)
+	if err != nil {
+		logrus.Errorf("Failed to get query: %s", err.Error())
+		return nil, err
+	}
+	md, err := v.MetadataWithParam(params)
+	if err != nil {
+		logrus.Errorf("Failed to get metadata: %s", err.Error())
+		return nil, err
+	}
+
+	m, err := v.getViewMetaDataIfExists(ctx, client)
+	if err != nil {
+		logrus.Errorf("Failed to get the metadata of this table: %s", err.Error())
+		return nil, err
+	}
+	if m != nil && !v.queryChanged(m.ViewQuery, q) {
+		v.ensureLineage(ctx, client, q)
+		return CheckColumnDocs(m.Schema, md.Schema), nil
+	}
+	stats, err := runDryRun(ctx, client, q)
+	if err != nil {
+		return nil, err
+	}
+	v.recordLineage(q, stats)
+	return CheckColumnDocs(stats.Schema, md.Schema), nil
+}
+
+func (v *ViewConfig) diffWithActualView(ctx context.Context, client *bigquery.Client, q string, md *Metadata) (*ViewDiff, error) {
 	dataset := client.Dataset(v.DatasetName)
-	if _, err = dataset.Metadata(ctx); err != nil && hasStatusCode(err, http.StatusNotFound) {
+	if _, err := dataset.Metadata(ctx); err != nil && hasStatusCode(err, http.StatusNotFound) {
 		return &ViewDiff{
-			ViewName:           v.ViewName,
-			DatasetName:        v.DatasetName,
-			OldViewQuery:       "",
-			NewViewQuery:       q,
-			MetadataUpdateFlag: false,
+			ViewName:     v.ViewName,
+			DatasetName:  v.DatasetName,
+			OldViewQuery: "",
+			NewViewQuery: q,
+			QueryChanged: true,
+			NewView:      true,
 		}, nil
 	}
 
@@ -250,146 +550,197 @@ func (v *ViewConfig) Diff(ctx context.Context, client *bigquery.Client, params m
 
 	if err != nil && hasStatusCode(err, http.StatusNotFound) {
 		return &ViewDiff{
-			ViewName:           v.ViewName,
-			DatasetName:        v.DatasetName,
-			OldViewQuery:       "",
-			NewViewQuery:       q,
-			MetadataUpdateFlag: false,
+			ViewName:     v.ViewName,
+			DatasetName:  v.DatasetName,
+			OldViewQuery: "",
+			NewViewQuery: q,
+			QueryChanged: true,
+			NewView:      true,
 		}, nil
 	}
+	if err != nil {
+		logrus.Errorf("Failed to get metadata of view(%s.%s): %s", v.DatasetName, v.ViewName, err.Error())
+		return nil, err
+	}
 
-	// parse metadata string from TableMetadata and file
-	newMetaByte := make([]byte, 0, 1024)
-	oldMetaByte := make([]byte, 0, 1024)
-	if &v.MetadataFromFile != nil {
-		// create old metadata string from metadata object
-		oldMetaByte = append(oldMetaByte, m.Description...)
-		for _, value := range m.Schema {
-			oldMetaByte = append(oldMetaByte, value.Description...)
-		}
-
-		var oldLabelStringList []string
-		for key, value := range m.Labels {
-			oldLabelStringList = append(oldLabelStringList, key+value)
-		}
-		sort.Strings(oldLabelStringList)
-		for _, keyValueString := range oldLabelStringList {
-			oldMetaByte = append(oldMetaByte, keyValueString...)
-		}
-
-		// create new metadata string from json struct
-		newMetaByte = append(newMetaByte, v.MetadataFromFile.Description...)
-		for _, value := range v.MetadataFromFile.Schema {
-			newMetaByte = append(newMetaByte, value.Description...)
-		}
-
-		var newLabelStringList []string
-		for key, value := range v.MetadataFromFile.Labels {
-			newLabelStringList = append(newLabelStringList, key+value)
-		}
-		sort.Strings(newLabelStringList)
-		for _, keyValueString := range newLabelStringList {
-			newMetaByte = append(newMetaByte, keyValueString...)
+	// The policy tags are read only if meta.json declares them, and left alone otherwise.
+	var currentTags map[string][]string
+	if len(md.ColumnPolicyTags()) > 0 {
+		if currentTags, err = getColumnPolicyTags(ctx, client, view); err != nil {
+			logrus.Errorf("Failed to get policy tags: %s", err.Error())
+			return nil, err
 		}
-
 	}
-	logrus.Debugf("Old metadata string:%s", string(oldMetaByte))
-	logrus.Debugf("New metadata string:%s", string(newMetaByte))
-	if (strings.Compare(m.ViewQuery, q) != 0) || !(bytes.Equal(oldMetaByte, newMetaByte)) {
+	labels, foreign, err := desiredLabels(m.Labels, md.Labels, v.Options.LabelMode)
+	if err != nil {
+		logrus.Errorf("Failed to decide labels: %s", err.Error())
+		return nil, err
+	}
+	changes, err := diffMetadata(m, md, v.ViewName, labels, currentTags, time.Now())
+	if err != nil {
+		logrus.Errorf("Failed to compare metadata: %s", err.Error())
+		return nil, err
+	}
+	for _, change := range changes {
+		logrus.Debugf("View(%s.%s) %s", v.DatasetName, v.ViewName, change)
+	}
+
+	queryChanged := v.queryChanged(m.ViewQuery, q)
+	if queryChanged || len(changes) > 0 {
 		return &ViewDiff{
-			ViewName:           v.ViewName,
-			DatasetName:        v.DatasetName,
-			OldViewQuery:       m.ViewQuery,
-			NewViewQuery:       q,
-			MetadataUpdateFlag: !bytes.Equal(oldMetaByte, newMetaByte),
+			ViewName:        v.ViewName,
+			DatasetName:     v.DatasetName,
+			OldViewQuery:    m.ViewQuery,
+			NewViewQuery:    q,
+			QueryChanged:    queryChanged,
+			MetadataChanges: changes,
+			ForeignLabels:   foreign,
+			OldSchema:       m.Schema,
 		}, nil
 	}
 	return nil, nil
 }
 
 // CreateViewConfigsFromDatasetDir creates ViewConfig objects defined in the given dir directory.
+// It keeps loading the other views when some files fail to load, and returns the views loaded successfully
+// together with a *LoadError listing every failing file.
 func CreateViewConfigsFromDatasetDir(dir string) ([]*ViewConfig, error) {
+	return CreateViewConfigsFromFS(os.DirFS(dir), dir)
+}
+
+// CreateViewConfigsFromFS creates ViewConfig objects defined in the filesystem fsys,
+// such as the tree at a git revision, a tarball or an in-memory tree, in the same way as CreateViewConfigsFromDatasetDir.
+// name is the path of the filesystem shown in the errors.
+func CreateViewConfigsFromFS(fsys fs.FS, name string) ([]*ViewConfig, error) {
+	tree := viewTree{fsys: fsys, name: name}
 	ret := make([]*ViewConfig, 0)
-	files, err := ioutil.ReadDir(dir)
+	files, err := fs.ReadDir(fsys, ".")
 	if err != nil {
-		logrus.Errorf("Failed to list files in dir: %s", dir)
+		logrus.Errorf("Failed to list files in dir: %s", name)
 		return nil, err
 	}
 
+	loadErr := new(LoadError)
 	for _, f := range files {
 		if !f.IsDir() {
 			continue
 		}
-		err := createViewConfigsFromViewDir(filepath.Join(dir, f.Name()), &ret, f.Name())
-		if err != nil {
-			logrus.Errorf("Failed to create views in the dir(%s): %s", filepath.Join(dir, f.Name()), err.Error())
-			// Keep creating ViewConfigs in the next dir instead of returning err.
-		}
+		createViewConfigsFromViewDir(tree, f.Name(), &ret, f.Name(), loadErr)
 	}
 
+	if len(loadErr.Errors) > 0 {
+		return ret, loadErr
+	}
 	return ret, nil
 }
 
-func createViewConfigsFromViewDir(dir string, ret *[]*ViewConfig, datasetName string) error {
-	files, err := ioutil.ReadDir(dir)
+// viewTree is a filesystem which holds the files of the views.
+type viewTree struct {
+	fsys fs.FS
+	// name is the path of the filesystem shown in the errors.
+	name string
+}
+
+// path returns the path of the file in the filesystem to be shown in the errors.
+func (t viewTree) path(name string) string {
+	return filepath.Join(t.name, filepath.FromSlash(name))
+}
+
+func createViewConfigsFromViewDir(tree viewTree, dir string, ret *[]*ViewConfig, datasetName string, loadErr *LoadError) {
+	files, err := fs.ReadDir(tree.fsys, dir)
 	if err != nil {
-		logrus.Errorf("Failed to list files in dir: %s", dir)
-		return err
+		loadErr.add(tree.path(dir), err)
+		return
+	}
+	// The views are skipped not to create the dataset without the metadata it's declared with.
+	datasetConfig, err := readDatasetConfig(tree, dir)
+	if err != nil {
+		loadErr.add(tree.path(dir), err)
+		return
 	}
 
 	for _, f := range files {
 		if !f.IsDir() {
 			continue
 		}
-		viewConfig, err := createViewConfigFromQueryFile(datasetName, f.Name(), filepath.Join(dir, f.Name(), "query.sql"), filepath.Join(dir, f.Name(), "meta.json"))
+		viewConfig, err := createViewConfigFromQueryFile(tree, datasetName, f.Name(), path.Join(dir, f.Name()))
 		if err != nil {
-			return err
+			loadErr.add(tree.path(path.Join(dir, f.Name())), err)
+			continue
 		}
 		if viewConfig == nil {
 			continue
 		}
+		viewConfig.Dataset = datasetConfig
 
 		*ret = append(*ret, viewConfig)
 	}
-
-	return nil
 }
 
-func createViewConfigFromQueryFile(datasetName, viewName, queryFileName, metadataFileName string) (*ViewConfig, error) {
+func createViewConfigFromQueryFile(tree viewTree, datasetName, viewName, dir string) (*ViewConfig, error) {
 	vc := new(ViewConfig)
 	vc.DatasetName = datasetName
 	vc.ViewName = viewName
 
-	if _, err := os.Stat(queryFileName); os.IsNotExist(err) {
+	query, sources, err := readViewFiles(tree, dir)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
 		logrus.Debugf("Query File not found. skip %s.%s", datasetName, viewName)
 		return nil, nil
 	}
+	vc.Query = *query
+	vc.MetadataSources = sources
 
-	queryFile, err := ioutil.ReadFile(queryFileName)
+	// The templates are rendered by MetadataWithParam once the params are known,
+	// and only the metadata without template actions is parsed here.
+	md, err := checkMetadata(sources)
 	if err != nil {
-		logrus.Errorf("Failed to open query file(%s): %s", queryFileName, err.Error())
 		return nil, err
 	}
+	if md != nil {
+		vc.MetadataFromFile = *md
+		logrus.Debugf("metadata from file(%s.%s):%s", vc.DatasetName, vc.ViewName, vc.MetadataFromFile)
+	}
 
-	query := string(queryFile[:])
-	vc.Query = query
+	return vc, nil
+}
 
-	if _, err := os.Stat(metadataFileName); os.IsNotExist(err) {
-		logrus.Debugf("Metadata file not found. skip load metadata from file: %s", metadataFileName)
-	} else {
-		metadataFile, err := ioutil.ReadFile(metadataFileName)
-		if err != nil {
-			logrus.Errorf("Failed to open metadata file(%s): %s", metadataFileName, err.Error())
-			return nil, err
+// readViewFiles reads query.sql and the metadata in meta.json, meta.yaml and the front-matter of query.sql in dir of the tree.
+// The front-matter is stripped from the returned query. The query is nil if dir has no query.sql.
+func readViewFiles(tree viewTree, dir string) (*string, []MetadataSource, error) {
+	queryFileName := path.Join(dir, "query.sql")
+	if _, err := fs.Stat(tree.fsys, queryFileName); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	queryFile, err := fs.ReadFile(tree.fsys, queryFileName)
+	if err != nil {
+		return nil, nil, &FileError{Path: tree.path(queryFileName), Err: err}
+	}
+
+	sources := make([]MetadataSource, 0)
+	for _, file := range []struct {
+		name   string
+		format MetadataFormat
+	}{{"meta.json", MetadataJSON}, {"meta.yaml", MetadataYAML}} {
+		name := path.Join(dir, file.name)
+		if _, err := fs.Stat(tree.fsys, name); os.IsNotExist(err) {
+			logrus.Debugf("Metadata file not found. skip load metadata from file: %s", tree.path(name))
+			continue
 		}
-		if err := json.Unmarshal(metadataFile, &vc.MetadataFromFile); err != nil {
-			logrus.Errorf("JSON Unmarshal error: file(%s): %s", metadataFileName, err.Error())
-			return nil, err
+		data, err := fs.ReadFile(tree.fsys, name)
+		if err != nil {
+			return nil, nil, &FileError{Path: tree.path(name), Err: err}
 		}
-		logrus.Debugf("metadata from file(%s.%s):%s", vc.DatasetName, vc.ViewName, vc.MetadataFromFile)
+		sources = append(sources, MetadataSource{Path: tree.path(name), Format: file.format, Template: string(data)})
 	}
 
-	return vc, nil
+	frontMatter, query, found := splitFrontMatter(string(queryFile))
+	if found {
+		sources = append(sources, MetadataSource{Path: tree.path(queryFileName), Format: MetadataYAML, Template: frontMatter})
+	}
+	return &query, sources, nil
 }
 
 // Copy and paste from go/bigquery/integration_test.go
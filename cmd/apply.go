@@ -33,11 +33,20 @@ var applyCmd = &cobra.Command{
 	Short: "Apply builds and updates thew views you defined.",
 	Long:  `Apply builds and updates thew views you defined.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		configs, err := bqv.CreateViewConfigsFromDatasetDir(baseDir)
+		configs, err := loadViewConfigs()
 		if err != nil {
 			logrus.Errorf("Failed to read views: %s", err.Error())
 			os.Exit(1)
 		}
+		if err = setOptions(configs); err != nil {
+			logrus.Errorf("Invalid options: %s", err.Error())
+			os.Exit(1)
+		}
+		selected, err := selectViewConfigs(configs)
+		if err != nil {
+			logrus.Errorf("Failed to select views: %s", err.Error())
+			os.Exit(1)
+		}
 
 		params, err := loadParamFile()
 		if err != nil {
@@ -45,33 +54,61 @@ var applyCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		cache, err := loadLineageCache()
+		if err != nil {
+			logrus.Errorf("Failed to read the lineage: %s", err.Error())
+			os.Exit(1)
+		}
+		for _, config := range configs {
+			config.Options.Lineage = cache
+		}
+
 		ctx := context.Background()
 
-		client, err := bigquery.NewClient(ctx, projectID)
+		client, err := bqv.NewClient(ctx, projectID)
 		if err != nil {
 			logrus.Errorf("Failed to create bigquery client: %s", err.Error())
 			os.Exit(1)
 		}
 
+		graph, err := newDependencyGraph(configs, params, cache)
+		if err != nil {
+			logrus.Errorf("Failed to build the dependency graph: %s", err.Error())
+			os.Exit(1)
+		}
+
 		errCount := 0
 		if dryRun {
-			for _, config := range configs {
-				if _, err = config.DryRun(ctx, client, params); err != nil {
+			pending, err := graph.PendingViews(ctx, client)
+			if err != nil {
+				logrus.Errorf("Failed to find the views to be changed: %s", err.Error())
+				os.Exit(1)
+			}
+			// The views out of the selection stay as they are, so their new queries mustn't be inlined.
+			pending = selectedPendingViews(pending, selected)
+			for _, config := range selected {
+				if _, err = graph.DryRun(ctx, client, config, params, pending); err != nil {
 					logrus.Errorf("Failed to create view %s.%s (dry-run): %s", config.DatasetName, config.ViewName, err.Error())
 					errCount++
 				}
 			}
+			saveLineageCache(cache)
+			if cache != nil {
+				// The graph already has the lineage loaded from the file.
+				graph.AddLineage(cache.Recorded())
+			}
+			reportUnknownReferences(ctx, client, graph, selected)
 			if deleteIfNotDefined {
 				logrus.Error("--delte-if-not-defined option's not implemented yet")
 				os.Exit(1)
 			}
 		} else {
-			for _, config := range configs {
-				if _, err = config.Apply(ctx, client, params); err != nil {
-					logrus.Errorf("Failed to create view %s.%s: %s", config.DatasetName, config.ViewName, err.Error())
-					errCount++
-				}
+			diffs := make(map[*bqv.ViewConfig]*bqv.ViewDiff)
+			if !force {
+				diffs = checkDownstreams(ctx, client, graph, selected, params)
 			}
+			errCount = graph.ApplyViews(ctx, client, selected, params, diffs)
+			saveLineageCache(cache)
 			if deleteIfNotDefined {
 				logrus.Error("--delte-if-not-defined option's not implemented yet")
 				os.Exit(1)
@@ -85,10 +122,77 @@ var applyCmd = &cobra.Command{
 	},
 }
 
+// checkDownstreams exits if any downstream view would fail against the new query of its selected upstream view.
+// It returns the diffs of the selected views, where nil means no change, for ApplyViews.
+func checkDownstreams(ctx context.Context, client *bigquery.Client, graph *bqv.DependencyGraph, selected []*bqv.ViewConfig, params map[string]string) map[*bqv.ViewConfig]*bqv.ViewDiff {
+	ret := make(map[*bqv.ViewConfig]*bqv.ViewDiff)
+	diffs := make([]*bqv.ViewDiff, 0)
+	for _, config := range selected {
+		diff, err := config.Diff(ctx, client, params)
+		if err != nil {
+			logrus.Errorf("Failed to create diff of view(%s.%s): %s", config.DatasetName, config.ViewName, err.Error())
+			os.Exit(1)
+		}
+		ret[config] = diff
+		if diff != nil {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	failures, err := graph.CheckDownstreams(ctx, client, diffs)
+	if err != nil {
+		logrus.Errorf("Failed to check downstream views: %s", err.Error())
+		os.Exit(1)
+	}
+	for _, failure := range failures {
+		logrus.Errorf("%s", failure.Error())
+	}
+	if len(failures) > 0 {
+		logrus.Errorf("Refused to apply because %d downstream views would break. Use --force to apply anyway", len(failures))
+		os.Exit(1)
+	}
+	return ret
+}
+
+// reportUnknownReferences warns about the tables the selected views reference
+// which are neither managed views nor existing tables.
+func reportUnknownReferences(ctx context.Context, client *bigquery.Client, graph *bqv.DependencyGraph, selected []*bqv.ViewConfig) {
+	for _, config := range selected {
+		unknown, err := graph.UnknownReferences(ctx, client, config)
+		if err != nil {
+			logrus.Warnf("Failed to check the references of view(%s.%s): %s", config.DatasetName, config.ViewName, err.Error())
+			continue
+		}
+		for _, ref := range unknown {
+			logrus.Warnf("View(%s.%s) references %s which is neither a managed view nor an existing table", config.DatasetName, config.ViewName, ref)
+		}
+	}
+}
+
+// selectedPendingViews returns the pending views which are selected.
+func selectedPendingViews(pending map[string]bool, selected []*bqv.ViewConfig) map[string]bool {
+	ret := make(map[string]bool)
+	for _, config := range selected {
+		key := config.DatasetName + "." + config.ViewName
+		if pending[key] {
+			ret[key] = true
+		}
+	}
+	return ret
+}
+
 func init() {
 	rootCmd.AddCommand(applyCmd)
 
 	applyCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name")
+	addSelectionFlags(applyCmd)
+	applyCmd.PersistentFlags().StringVar(&labelMode, "label-mode", "replace", "How to manage labels. \"replace\" replaces all the labels and \"owned\" touches only the labels bqv set")
+	applyCmd.PersistentFlags().BoolVar(&rawQueryDiff, "raw-query-diff", false, "Compare the queries byte for byte instead of ignoring whitespace and comments")
+	applyCmd.PersistentFlags().Int64Var(&maxBytes, "max-bytes", 0, "Fail if a full SELECT * on a changed view would process more bytes than this (0 means no limit)")
+	applyCmd.PersistentFlags().Float64Var(&maxCostGrowth, "max-cost-growth", 0, "Fail if the bytes processed by a changed view would grow by more than this percentage (0 means no limit)")
 	applyCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Dry run")
+	applyCmd.PersistentFlags().BoolVar(&validateAll, "validate-all", false, "Validate the queries of all the views in dry run even if they haven't changed")
+	applyCmd.PersistentFlags().BoolVar(&strictDocs, "strict-docs", false, "Treat the mismatches between the documented columns and the actual ones as errors")
+	applyCmd.PersistentFlags().BoolVar(&force, "force", false, "Apply the views even if they break their contracts or their downstream views")
 	applyCmd.PersistentFlags().BoolVar(&deleteIfNotDefined, "delete-if-not-defined", false, "Delete views if they're not defined")
 }
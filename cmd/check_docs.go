@@ -0,0 +1,89 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var checkDocsCmd = &cobra.Command{
+	Use:   "check-docs",
+	Short: "Check-docs compares the columns documented in meta.json with the actual ones.",
+	Long: `Check-docs compares the columns documented in meta.json with the actual ones.
+It reports the documented columns the views don't produce and the columns which aren't documented.
+The columns are taken from the actual view, or from a dry run of the query if it has changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configs, err := loadViewConfigs()
+		if err != nil {
+			logrus.Errorf("Failed to read views: %s", err.Error())
+			os.Exit(1)
+		}
+		if err = setOptions(configs); err != nil {
+			logrus.Errorf("Invalid options: %s", err.Error())
+			os.Exit(1)
+		}
+
+		params, err := loadParamFile()
+		if err != nil {
+			logrus.Errorf("Failed to read parameteer file: %s", err.Error())
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+
+		client, err := bqv.NewClient(ctx, projectID)
+		if err != nil {
+			logrus.Errorf("Failed to create bigquery client: %s", err.Error())
+			os.Exit(1)
+		}
+
+		errCount := 0
+		issueCount := 0
+		for _, config := range configs {
+			issues, err := config.CheckDocs(ctx, client, params)
+			if err != nil {
+				logrus.Errorf("Failed to check the documentation of view(%s.%s): %s", config.DatasetName, config.ViewName, err.Error())
+				errCount++
+				continue
+			}
+			for _, issue := range issues {
+				fmt.Printf("%s.%s: %s\n", config.DatasetName, config.ViewName, issue)
+			}
+			issueCount += len(issues)
+		}
+
+		if errCount > 0 {
+			logrus.Errorf("%d errors occured", errCount)
+			os.Exit(1)
+		}
+		if strictDocs && issueCount > 0 {
+			logrus.Errorf("%d documentation issues were found", issueCount)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkDocsCmd)
+
+	checkDocsCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name")
+	checkDocsCmd.PersistentFlags().BoolVar(&strictDocs, "strict-docs", false, "Exit with an error if any documentation issue is found")
+}
@@ -18,7 +18,6 @@ import (
 	"context"
 	"os"
 
-	"cloud.google.com/go/bigquery"
 	"github.com/k-kawa/bqv/bqv"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -32,13 +31,18 @@ var destroyCmd = &cobra.Command{
 	Short: "Destroy deletes all the views you defined.",
 	Long:  `Destroy deletes all the views you defined`,
 	Run: func(cmd *cobra.Command, args []string) {
-		configs, err := bqv.CreateViewConfigsFromDatasetDir(baseDir)
+		configs, err := loadViewConfigs()
 		if err != nil {
 			logrus.Errorf("Failed to read views: %s", err.Error())
 			os.Exit(1)
 		}
+		selected, err := selectViewConfigs(configs)
+		if err != nil {
+			logrus.Errorf("Failed to select views: %s", err.Error())
+			os.Exit(1)
+		}
 		ctx := context.Background()
-		client, err := bigquery.NewClient(ctx, projectID)
+		client, err := bqv.NewClient(ctx, projectID)
 		if err != nil {
 			logrus.Errorf("Failed to create bigquery client: %s", err.Error())
 			os.Exit(1)
@@ -56,7 +60,7 @@ var destroyCmd = &cobra.Command{
 				os.Exit(1)
 			}
 		} else {
-			for _, config := range configs {
+			for _, config := range selected {
 				if _, err = config.DeleteIfExist(ctx, client); err != nil {
 					logrus.Errorf("Failed to delete a view %s.%s: %s", config.DatasetName, config.ViewName, err.Error())
 					errCount++
@@ -75,5 +79,6 @@ var destroyCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(destroyCmd)
 	destroyCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name")
+	addSelectionFlags(destroyCmd)
 	destroyCmd.PersistentFlags().BoolVar(&all, "all", false, "Delete all the views which are not defined.")
 }
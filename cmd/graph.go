@@ -0,0 +1,99 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var graphFormat string
+var focus string
+var upstream bool
+var downstream bool
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Graph shows how the views depend on each other and on the tables bqv doesn't manage.",
+	Long: `Graph shows how the views depend on each other and on the tables bqv doesn't manage
+in Graphviz DOT, Mermaid or JSON. The arrows point from the tables to the views which select from them.
+--focus shows only the view and its upstream views and downstream views.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		params, err := loadParamFile()
+		if err != nil {
+			logrus.Errorf("Failed to read parameteer file: %s", err.Error())
+			os.Exit(1)
+		}
+		configs, err := loadViewConfigs()
+		if err != nil {
+			logrus.Errorf("Failed to read views: %s", err.Error())
+			os.Exit(1)
+		}
+		cache, err := loadLineageCache()
+		if err != nil {
+			logrus.Errorf("Failed to read the lineage: %s", err.Error())
+			os.Exit(1)
+		}
+		graph, err := newDependencyGraph(configs, params, cache)
+		if err != nil {
+			logrus.Errorf("Failed to build the dependency graph: %s", err.Error())
+			os.Exit(1)
+		}
+
+		var focusConfig *bqv.ViewConfig
+		if focus != "" {
+			names := strings.SplitN(focus, ".", 2)
+			if len(names) == 2 {
+				focusConfig = findViewConfig(configs, names[0], names[1])
+			}
+			if focusConfig == nil {
+				logrus.Errorf("View(%s) was not found", focus)
+				os.Exit(1)
+			}
+		}
+
+		export := graph.Export(focusConfig, upstream, downstream)
+		switch graphFormat {
+		case "dot":
+			fmt.Print(export.DOT())
+		case "mermaid":
+			fmt.Print(export.Mermaid())
+		case "json":
+			s, err := export.JSON()
+			if err != nil {
+				logrus.Errorf("%s", err.Error())
+				os.Exit(1)
+			}
+			fmt.Print(s)
+		default:
+			logrus.Errorf("Unknown format: %s", graphFormat)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name. The references to other projects aren't regarded as the managed views")
+	graphCmd.PersistentFlags().StringVar(&graphFormat, "format", "dot", "Output format: dot, mermaid or json")
+	graphCmd.PersistentFlags().StringVar(&focus, "focus", "", "Show only the view in (dataset).(view) format and its upstream and downstream views")
+	graphCmd.PersistentFlags().BoolVar(&upstream, "upstream", false, "Show only the upstream views of the focus view")
+	graphCmd.PersistentFlags().BoolVar(&downstream, "downstream", false, "Show only the downstream views of the focus view")
+}
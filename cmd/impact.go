@@ -0,0 +1,96 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var impactJSON bool
+
+var impactCmd = &cobra.Command{
+	Use:   "impact (project).(dataset).(table)",
+	Short: "Impact shows the views which select from the table directly or transitively.",
+	Long: `Impact shows the views which select from the table directly or transitively
+with the shortest path from the table to each of them, by searching the queries rendered with the parameter file.
+A reference without the project in the queries matches the table in the project given by --projectID.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("(project).(dataset).(table) name is required")
+		}
+		_, err := bqv.ParseTableRef(args[0])
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		table, _ := bqv.ParseTableRef(args[0])
+
+		params, err := loadParamFile()
+		if err != nil {
+			logrus.Errorf("Failed to read parameteer file: %s", err.Error())
+			os.Exit(1)
+		}
+		configs, err := loadViewConfigs()
+		if err != nil {
+			logrus.Errorf("Failed to read views: %s", err.Error())
+			os.Exit(1)
+		}
+		cache, err := loadLineageCache()
+		if err != nil {
+			logrus.Errorf("Failed to read the lineage: %s", err.Error())
+			os.Exit(1)
+		}
+		graph, err := newDependencyGraph(configs, params, cache)
+		if err != nil {
+			logrus.Errorf("Failed to build the dependency graph: %s", err.Error())
+			os.Exit(1)
+		}
+
+		impacts := graph.Impact(table)
+		if impactJSON {
+			data, err := json.MarshalIndent(impacts, "", "  ")
+			if err != nil {
+				logrus.Errorf("%s", err.Error())
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+		if len(impacts) == 0 {
+			fmt.Printf("No managed view selects from %s\n", table)
+			return
+		}
+		for _, impact := range impacts {
+			kind := "transitive"
+			if impact.Direct {
+				kind = "direct"
+			}
+			fmt.Printf("%s (%s): %s\n", impact.View, kind, strings.Join(impact.Path, " -> "))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+	impactCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name which the references without the project point to")
+	impactCmd.PersistentFlags().BoolVar(&impactJSON, "json", false, "Print the result in JSON")
+}
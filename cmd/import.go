@@ -0,0 +1,81 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var overwrite bool
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import writes the existing views in the project into the basedir.",
+	Long: `Import writes the existing views in the project into the basedir as (dataset)/(view)/query.sql and meta.json.
+meta.json holds the description, the column descriptions, the policy tags and the labels of the view,
+so that bqv plan against the same project shows no change right after the import.
+The views which already exist in the basedir are skipped unless --overwrite is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		selection := bqv.Selection{Targets: targets, Excludes: excludes}
+		if err := selection.Validate(); err != nil {
+			logrus.Errorf("%s", err.Error())
+			os.Exit(1)
+		}
+		ctx := context.Background()
+		client, err := bqv.NewClient(ctx, projectID)
+		if err != nil {
+			logrus.Errorf("Failed to create bigquery client: %s", err.Error())
+			os.Exit(1)
+		}
+
+		views, err := bqv.ImportViews(ctx, client, selection)
+		if err != nil {
+			logrus.Errorf("Failed to list views: %s", err.Error())
+			os.Exit(1)
+		}
+
+		errCount := 0
+		for _, view := range views {
+			written, err := bqv.WriteImportedView(baseDir, view, overwrite)
+			if err != nil {
+				logrus.Errorf("Failed to write view(%s.%s): %s", view.DatasetName, view.ViewName, err.Error())
+				errCount++
+				continue
+			}
+			if !written {
+				logrus.Infof("Skipping view(%s.%s). It already exists in the basedir.", view.DatasetName, view.ViewName)
+				continue
+			}
+			logrus.Infof("Imported view(%s.%s)", view.DatasetName, view.ViewName)
+		}
+		if errCount > 0 {
+			logrus.Errorf("%d views failed to be imported", errCount)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name")
+	importCmd.PersistentFlags().StringArrayVar(&targets, "target", nil, "Import only the views whose (dataset).(view) matches the glob pattern such as sales.* (repeatable)")
+	importCmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "Skip the views whose (dataset).(view) matches the glob pattern (repeatable)")
+	importCmd.PersistentFlags().BoolVar(&overwrite, "overwrite", false, "Overwrite the views which already exist in the basedir")
+}
@@ -18,7 +18,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/k-kawa/bqv/bqv"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -29,12 +28,17 @@ var listCmd = &cobra.Command{
 	Short: "List shows all the views to be managed.",
 	Long:  `List shows all the views to be managed in (dataset).(view) format.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		configs, err := bqv.CreateViewConfigsFromDatasetDir(baseDir)
+		configs, err := loadViewConfigs()
 		if err != nil {
 			logrus.Errorf("Failed to read views: %s", err.Error())
 			os.Exit(1)
 		}
-		for _, config := range configs {
+		selected, err := selectViewConfigs(configs)
+		if err != nil {
+			logrus.Errorf("Failed to select views: %s", err.Error())
+			os.Exit(1)
+		}
+		for _, config := range selected {
 			fmt.Printf("%s.%s\n", config.DatasetName, config.ViewName)
 		}
 	},
@@ -42,4 +46,5 @@ var listCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+	addSelectionFlags(listCmd)
 }
@@ -0,0 +1,73 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var piiAttribute string
+var piiValue string
+
+var piiCmd = &cobra.Command{
+	Use:   "pii",
+	Short: "Pii lists all the view columns classified as PII.",
+	Long: `Pii lists all the view columns classified as PII in (dataset).(view).(column) format.
+A column is classified as PII when the attribute given by --attribute has the value given by --value in meta.json.
+It reads meta.json files only and doesn't access BigQuery.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configs, err := loadViewConfigs()
+		if err != nil {
+			logrus.Errorf("Failed to read views: %s", err.Error())
+			os.Exit(1)
+		}
+
+		params, err := loadParamFile()
+		if err != nil {
+			logrus.Errorf("Failed to read parameteer file: %s", err.Error())
+			os.Exit(1)
+		}
+		for _, config := range configs {
+			md, err := config.MetadataWithParam(params)
+			if err != nil {
+				logrus.Errorf("Failed to read metadata of view(%s.%s): %s", config.DatasetName, config.ViewName, err.Error())
+				os.Exit(1)
+			}
+			config.MetadataFromFile = *md
+		}
+
+		for _, c := range bqv.FindClassifiedColumns(configs, piiAttribute, piiValue) {
+			line := fmt.Sprintf("%s.%s.%s\tpolicyTags=%s", c.DatasetName, c.ViewName, c.Column.Name, strings.Join(c.Column.PolicyTags, ","))
+			// The owner is printed only if the column or the view declares one.
+			if c.Owner != "" {
+				line += "\towner=" + c.Owner
+			}
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(piiCmd)
+
+	piiCmd.PersistentFlags().StringVar(&piiAttribute, "attribute", "sensitivity", "Name of the column attribute which classifies columns")
+	piiCmd.PersistentFlags().StringVar(&piiValue, "value", "pii", "Value of the attribute which means the column is PII")
+}
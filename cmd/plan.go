@@ -18,7 +18,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 
 	"cloud.google.com/go/bigquery"
@@ -33,6 +32,10 @@ var planCmd = &cobra.Command{
 	Short: "Plan shows what's going to happen if you run Apply.",
 	Long:  `Plan shows what's going to happen if you run Apply.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkNestingFlag(); err != nil {
+			logrus.Errorf("%s", err.Error())
+			os.Exit(1)
+		}
 		params, err := loadParamFile()
 		if err != nil {
 			logrus.Errorf("Failed to read parameteer file: %s", err.Error())
@@ -41,19 +44,43 @@ var planCmd = &cobra.Command{
 
 		ctx := context.Background()
 
-		client, err := bigquery.NewClient(ctx, projectID)
+		client, err := bqv.NewClient(ctx, projectID)
 		if err != nil {
 			logrus.Errorf("Failed to create bigquery client: %s", err.Error())
 			os.Exit(1)
 		}
 
-		configs, err := bqv.CreateViewConfigsFromDatasetDir(baseDir)
+		configs, err := loadViewConfigs()
 		if err != nil {
 			logrus.Errorf("Failed to read views: %s", err.Error())
 			os.Exit(1)
 		}
+		if err = setOptions(configs); err != nil {
+			logrus.Errorf("Invalid options: %s", err.Error())
+			os.Exit(1)
+		}
+		selected, err := selectViewConfigs(configs)
+		if err != nil {
+			logrus.Errorf("Failed to select views: %s", err.Error())
+			os.Exit(1)
+		}
 
+		cache, err := loadLineageCache()
+		if err != nil {
+			logrus.Errorf("Failed to read the lineage: %s", err.Error())
+			os.Exit(1)
+		}
 		for _, config := range configs {
+			config.Options.Lineage = cache
+		}
+		graph, err := newDependencyGraph(configs, params, cache)
+		if err != nil {
+			logrus.Errorf("Failed to build the dependency graph: %s", err.Error())
+			os.Exit(1)
+		}
+
+		issueCount := checkNesting(ctx, client, graph, selected)
+		for _, config := range selected {
 			diff, err := config.Diff(ctx, client, params)
 			if err != nil {
 				logrus.Errorf("Failed to create diff of view(%s.%s): %s", config.DatasetName, config.ViewName, err.Error())
@@ -62,20 +89,125 @@ var planCmd = &cobra.Command{
 			if diff == nil {
 				continue
 			}
-			queryDiff := "A view query has no change."
-			if strings.Compare(diff.OldViewQuery, diff.NewViewQuery) != 0 {
-				queryDiff = "### Old\n```sql\n" + diff.OldViewQuery + "\n```\n### New\n```sql\n" + diff.NewViewQuery + "\n```\n"
+			fmt.Print(formatViewDiff(diff))
+			if diff.HasBreakingChanges() {
+				fmt.Print(formatDownstreams(graph.AllDownstreams(config)))
+			}
+			if strictDocs {
+				issueCount += len(diff.DocIssues)
 			}
-			fmt.Printf("## %s.%s\n%s\nmetadata update :%s\n",
-				diff.DatasetName,
-				diff.ViewName,
-				queryDiff,
-				strconv.FormatBool(diff.MetadataUpdateFlag),
-			)
+			if err := config.CheckCost(diff.Cost); err != nil {
+				logrus.Errorf("%s", err.Error())
+				issueCount++
+			}
+		}
+		saveLineageCache(cache)
+		if cache != nil {
+			// The graph already has the lineage loaded from the file.
+			graph.AddLineage(cache.Recorded())
+		}
+		reportUnknownReferences(ctx, client, graph, selected)
+		if issueCount > 0 {
+			logrus.Errorf("%d issues were found", issueCount)
+			os.Exit(1)
 		}
 	},
 }
 
+// checkNesting logs the cycles and the views nesting too many levels of views which involve the selected views,
+// and returns the number of them.
+func checkNesting(ctx context.Context, client *bigquery.Client, graph *bqv.DependencyGraph, selected []*bqv.ViewConfig) int {
+	keys := make(map[string]bool)
+	for _, config := range selected {
+		keys[config.DatasetName+"."+config.ViewName] = true
+	}
+	count := 0
+	for _, cycle := range graph.Cycles() {
+		for _, key := range cycle {
+			if keys[key] {
+				logrus.Errorf("%s", cycle)
+				count++
+				break
+			}
+		}
+	}
+	depths := graph.ExternalViewDepths(ctx, client, selected)
+	for _, issue := range graph.CheckNesting(maxNestingDepth, depths) {
+		if keys[issue.View] {
+			logrus.Errorf("%s", issue)
+			count++
+		}
+	}
+	return count
+}
+
+// formatViewDiff returns the diff in markdown.
+func formatViewDiff(diff *bqv.ViewDiff) string {
+	queryDiff := "A view query has no change."
+	if diff.QueryChanged {
+		queryDiff = "### Old\n```sql\n" + diff.OldViewQuery + "\n```\n### New\n```sql\n" + diff.NewViewQuery + "\n```\n"
+	}
+	metadataDiff := "A view metadata has no change.\n"
+	if len(diff.MetadataChanges) > 0 {
+		metadataDiff = "### Metadata\n"
+		for _, change := range diff.MetadataChanges {
+			metadataDiff += "- " + change.String() + "\n"
+		}
+	}
+	if len(diff.SchemaChanges) > 0 {
+		metadataDiff += "### Schema\n"
+		for _, change := range diff.SchemaChanges {
+			if change.Breaking() {
+				metadataDiff += "- **" + change.String() + "**\n"
+			} else {
+				metadataDiff += "- " + change.String() + "\n"
+			}
+		}
+	}
+	if diff.Cost != nil {
+		metadataDiff += "### Cost\n- " + diff.Cost.String() + "\n"
+		for _, table := range diff.Cost.ReferencedTables {
+			metadataDiff += "- reads " + table.String() + "\n"
+		}
+	}
+	if diff.DryRunError != nil {
+		metadataDiff += "### Dry run failed\n" + diff.DryRunError.Error() + "\n"
+	}
+	if len(diff.ContractViolations) > 0 {
+		metadataDiff += "### Contract violations\n"
+		for _, violation := range diff.ContractViolations {
+			metadataDiff += "- " + violation.String() + "\n"
+		}
+	}
+	if len(diff.DocIssues) > 0 {
+		metadataDiff += "### Documentation issues\n"
+		for _, issue := range diff.DocIssues {
+			metadataDiff += "- " + issue.String() + "\n"
+		}
+	}
+	if len(diff.ForeignLabels) > 0 {
+		metadataDiff += "Labels left alone: " + strings.Join(diff.ForeignLabels, ", ") + "\n"
+	}
+	return fmt.Sprintf("## %s.%s\n%s\n%s",
+		diff.DatasetName,
+		diff.ViewName,
+		queryDiff,
+		metadataDiff,
+	)
+}
+
+// formatDownstreams returns the list of the views affected by breaking changes in markdown.
+func formatDownstreams(downstreams []*bqv.ViewConfig) string {
+	if len(downstreams) == 0 {
+		return "No managed view selects from this view.\n"
+	}
+	ret := "### Affected downstream views\n"
+	for _, downstream := range downstreams {
+		ret += fmt.Sprintf("- %s.%s\n", downstream.DatasetName, downstream.ViewName)
+	}
+	return ret
+}
+
 func init() {
 	rootCmd.AddCommand(planCmd)
 
@@ -85,6 +217,13 @@ func init() {
 	// and all subcommands, e.g.:
 	// planCmd.PersistentFlags().String("foo", "", "A help for foo")
 	planCmd.PersistentFlags().StringVar(&projectID, "projectID", "", "GCP project name")
+	addSelectionFlags(planCmd)
+	addNestingFlag(planCmd)
+	planCmd.PersistentFlags().StringVar(&labelMode, "label-mode", "replace", "How to manage labels. \"replace\" replaces all the labels and \"owned\" touches only the labels bqv set")
+	planCmd.PersistentFlags().BoolVar(&strictDocs, "strict-docs", false, "Treat the mismatches between the documented columns and the actual ones as errors")
+	planCmd.PersistentFlags().BoolVar(&rawQueryDiff, "raw-query-diff", false, "Compare the queries byte for byte instead of ignoring whitespace and comments")
+	planCmd.PersistentFlags().Int64Var(&maxBytes, "max-bytes", 0, "Fail if a full SELECT * on a changed view would process more bytes than this (0 means no limit)")
+	planCmd.PersistentFlags().Float64Var(&maxCostGrowth, "max-cost-growth", 0, "Fail if the bytes processed by a changed view would grow by more than this percentage (0 means no limit)")
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
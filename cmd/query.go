@@ -30,10 +30,14 @@ import (
 var queryCmd = &cobra.Command{
 	Use:   "query",
 	Short: "Query show the SQL made from the SQL template and the paramter file.",
-	Long:  `Query show the SQL made from the SQL template and the paramter file.`,
+	Long: `Query show the SQL made from the SQL template and the paramter file.
+Give (dataset).(view) to show the query of the view, or --target or --changed-since to show the queries of all the selected views.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
-			return errors.New("dataset.view name")
+			if len(targets) > 0 || changedSince != "" {
+				return nil
+			}
+			return errors.New("give (dataset).(view), --target or --changed-since to select the views")
 		}
 		name := args[0]
 		ptn := regexp.MustCompile("^([^.]+)\\.([^.]+)$")
@@ -44,12 +48,35 @@ var queryCmd = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		configs, err := bqv.CreateViewConfigsFromDatasetDir(baseDir)
+		configs, err := loadViewConfigs()
 		if err != nil {
 			logrus.Errorf("Failed to read views: %s", err.Error())
 			os.Exit(1)
 		}
 
+		params, err := loadParamFile()
+		if err != nil {
+			logrus.Errorf("%s", err.Error())
+			os.Exit(1)
+		}
+
+		if len(args) < 1 {
+			selected, err := selectViewConfigs(configs)
+			if err != nil {
+				logrus.Errorf("Failed to select views: %s", err.Error())
+				os.Exit(1)
+			}
+			for _, config := range selected {
+				q, err := config.QueryWithParam(params)
+				if err != nil {
+					logrus.Errorf("%s", err.Error())
+					os.Exit(1)
+				}
+				fmt.Printf("-- %s.%s\n%s\n", config.DatasetName, config.ViewName, strings.TrimRight(q, "\n"))
+			}
+			return
+		}
+
 		names := strings.Split(args[0], ".")
 
 		viewConfig := findViewConfig(configs, names[0], names[1])
@@ -58,12 +85,6 @@ var queryCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		params, err := loadParamFile()
-		if err != nil {
-			logrus.Errorf("%s", err.Error())
-			os.Exit(1)
-		}
-
 		q, err := viewConfig.QueryWithParam(params)
 		if err != nil {
 			logrus.Errorf("%s", err.Error())
@@ -76,6 +97,7 @@ var queryCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(queryCmd)
+	addSelectionFlags(queryCmd)
 }
 
 func findViewConfig(viewConfigs []*bqv.ViewConfig, datasetName, viewName string) *bqv.ViewConfig {
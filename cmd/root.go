@@ -17,9 +17,11 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
 
+	"github.com/k-kawa/bqv/bqv"
 	"github.com/sirupsen/logrus"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -32,6 +34,22 @@ var baseDir string
 var verbose bool
 var paramFile string
 var projectID string
+var labelMode string
+var rawQueryDiff bool
+var force bool
+var strictDocs bool
+var validateAll bool
+var maxBytes int64
+var maxCostGrowth float64
+var allowPartial bool
+var rev string
+var targets []string
+var excludes []string
+var withUpstreams bool
+var withDownstreams bool
+var changedSince string
+var lineageFile string
+var maxNestingDepth int
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -66,6 +84,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&baseDir, "basedir", ".", "Basedir of the views (default is the current dir")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Log option")
 	rootCmd.PersistentFlags().StringVar(&paramFile, "paramFile", ".params", "Path to paramegter file")
+	rootCmd.PersistentFlags().BoolVar(&allowPartial, "allow-partial", false, "Keep going with the views loaded successfully when some files fail to load")
+	rootCmd.PersistentFlags().StringVar(&rev, "rev", "", "Read the views in the basedir at the git revision such as a tag or a commit instead of the working tree")
+	rootCmd.PersistentFlags().StringVar(&lineageFile, "lineage-file", "", "Path to the file caching the tables BigQuery reports in dry runs such as .bqv-lineage.json (disabled if empty)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -111,3 +132,141 @@ func loadParamFile() (map[string]string, error) {
 
 	return ret, nil
 }
+
+// viewFS returns the filesystem of the basedir, at the revision if --rev is given, and its path shown in the errors.
+func viewFS() (fs.FS, string, error) {
+	if rev == "" {
+		return os.DirFS(baseDir), baseDir, nil
+	}
+	fsys, err := bqv.NewGitFS(baseDir, rev)
+	if err != nil {
+		return nil, "", err
+	}
+	return fsys, rev + ":" + baseDir, nil
+}
+
+// loadViewConfigs reads the views in the basedir, at the revision if --rev is given, and logs every file which fails to load.
+// It returns an error when some files fail to load unless --allow-partial is given.
+func loadViewConfigs() ([]*bqv.ViewConfig, error) {
+	fsys, name, err := viewFS()
+	if err != nil {
+		return nil, err
+	}
+	configs, err := bqv.CreateViewConfigsFromFS(fsys, name)
+	loadErr, ok := err.(*bqv.LoadError)
+	if !ok {
+		return configs, err
+	}
+	for _, fileErr := range loadErr.Errors {
+		logrus.Errorf("Failed to load %s", fileErr.Error())
+	}
+	if allowPartial {
+		logrus.Warnf("Ignoring %d files which failed to load", len(loadErr.Errors))
+		return configs, nil
+	}
+	return nil, loadErr
+}
+
+// addSelectionFlags adds the flags which select the views the command works on.
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringArrayVar(&targets, "target", nil, "Work only on the views whose (dataset).(view) matches the glob pattern such as sales.* (repeatable)")
+	cmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "Skip the views whose (dataset).(view) matches the glob pattern (repeatable)")
+	cmd.PersistentFlags().BoolVar(&withUpstreams, "with-upstreams", false, "Also work on the managed views the targets select from directly or transitively")
+	cmd.PersistentFlags().BoolVar(&withDownstreams, "with-downstreams", false, "Also work on the managed views which select from the targets directly or transitively")
+	cmd.PersistentFlags().StringVar(&changedSince, "changed-since", "", "Work only on the views whose files or params changed since the merge base of the git ref and HEAD")
+}
+
+// selectViewConfigs returns the configs selected by --target, --exclude, --changed-since, --with-upstreams and --with-downstreams.
+func selectViewConfigs(configs []*bqv.ViewConfig) ([]*bqv.ViewConfig, error) {
+	selection := bqv.Selection{Targets: targets, Excludes: excludes, WithUpstreams: withUpstreams, WithDownstreams: withDownstreams}
+	if err := selection.Validate(); err != nil {
+		return nil, err
+	}
+	if !withUpstreams && !withDownstreams && changedSince == "" {
+		return selection.Select(configs, nil), nil
+	}
+
+	params, err := loadParamFile()
+	if err != nil {
+		return nil, err
+	}
+	if changedSince != "" {
+		changes, err := bqv.NewGitChanges(baseDir, changedSince)
+		if err != nil {
+			return nil, err
+		}
+		if selection.Changed, err = changes.ChangedViews(configs, baseDir, paramFile, params); err != nil {
+			return nil, err
+		}
+	}
+	cache, err := loadLineageCache()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := newDependencyGraph(configs, params, cache)
+	if err != nil {
+		return nil, err
+	}
+	return selection.Select(configs, graph), nil
+}
+
+// loadLineageCache reads the file given by --lineage-file. It returns nil if --lineage-file is empty.
+func loadLineageCache() (*bqv.LineageCache, error) {
+	if lineageFile == "" {
+		return nil, nil
+	}
+	return bqv.LoadLineageCache(lineageFile)
+}
+
+// saveLineageCache writes the cache back to the file given by --lineage-file.
+func saveLineageCache(cache *bqv.LineageCache) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Save(lineageFile); err != nil {
+		logrus.Warnf("Failed to save the lineage to %s: %s", lineageFile, err.Error())
+	}
+}
+
+// newDependencyGraph builds the dependency graph of the views and adds the cached lineage to it if cache isn't nil.
+func newDependencyGraph(configs []*bqv.ViewConfig, params map[string]string, cache *bqv.LineageCache) (*bqv.DependencyGraph, error) {
+	graph, err := bqv.NewDependencyGraph(configs, params, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		graph.AddLineage(cache)
+	}
+	return graph, nil
+}
+
+// addNestingFlag adds the flag which lowers the limit of the levels of nested views.
+func addNestingFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&maxNestingDepth, "max-nesting-depth", bqv.MaxNestingDepth, fmt.Sprintf("Report the views which nest more levels of views than this (up to %d, the limit of BigQuery)", bqv.MaxNestingDepth))
+}
+
+// checkNestingFlag returns an error if --max-nesting-depth is out of the range BigQuery accepts.
+func checkNestingFlag() error {
+	if maxNestingDepth < 1 || maxNestingDepth > bqv.MaxNestingDepth {
+		return fmt.Errorf("--max-nesting-depth must be between 1 and %d but got %d", bqv.MaxNestingDepth, maxNestingDepth)
+	}
+	return nil
+}
+
+// setOptions sets the options given by the flags to the configs.
+func setOptions(configs []*bqv.ViewConfig) error {
+	mode, err := bqv.ParseLabelMode(labelMode)
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		config.Options.LabelMode = mode
+		config.Options.RawQueryComparison = rawQueryDiff
+		config.Options.Force = force
+		config.Options.StrictDocs = strictDocs
+		config.Options.ValidateAll = validateAll
+		config.Options.MaxBytesProcessed = maxBytes
+		config.Options.MaxCostGrowthPercent = maxCostGrowth
+	}
+	return nil
+}
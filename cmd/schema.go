@@ -0,0 +1,49 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [meta.json|dataset.json]",
+	Short: "Schema prints the JSON Schema of meta.json or dataset.json.",
+	Long: `Schema prints the JSON Schema of meta.json, or the one of dataset.json if it's given.
+Save it and point your editor at it to complete and check the file while you write it.
+bqv validates meta.json and dataset.json against the same schemas.
+meta.yaml and the front-matter of query.sql hold the same fields as meta.json in YAML.`,
+	ValidArgs: []string{"meta.json", "dataset.json"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		return cobra.OnlyValidArgs(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 && args[0] == "dataset.json" {
+			fmt.Print(bqv.DatasetSchema)
+			return
+		}
+		fmt.Print(bqv.MetadataSchema)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,66 @@
+// Copyright © 2019 Kohei Kawasaki <mynameiskawasaq@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/k-kawa/bqv/bqv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate checks the views you defined without accessing BigQuery.",
+	Long: `Validate checks the views you defined without accessing BigQuery.
+It renders query.sql and meta.json with the parameter file, validates meta.json against the schema bqv schema prints,
+and checks the names of the datasets and the views and the length of the queries against the limits of BigQuery.
+It also reports the circular references between the views and the views which nest more levels of views than --max-nesting-depth.
+With --rev, it checks the views at the git revision instead of the working tree.
+It reports every problem with the path of the file and the line and the column in it if they are known.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkNestingFlag(); err != nil {
+			logrus.Errorf("%s", err.Error())
+			os.Exit(1)
+		}
+		params, err := loadParamFile()
+		if err != nil {
+			logrus.Errorf("Failed to read parameteer file: %s", err.Error())
+			os.Exit(1)
+		}
+
+		fsys, name, err := viewFS()
+		if err != nil {
+			logrus.Errorf("Failed to read the views: %s", err.Error())
+			os.Exit(1)
+		}
+		problems := bqv.ValidateFS(fsys, name, params)
+		problems = append(problems, bqv.ValidateDependencies(fsys, name, params, maxNestingDepth)...)
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			logrus.Errorf("%d problems were found", len(problems))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	addNestingFlag(validateCmd)
+}
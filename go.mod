@@ -1,5 +1,7 @@
 module github.com/k-kawa/bqv
 
+go 1.16
+
 require (
 	cloud.google.com/go v0.34.0
 	github.com/googleapis/gax-go v2.0.2+incompatible // indirect
@@ -13,4 +15,5 @@ require (
 	google.golang.org/api v0.0.0-20181217000635-41dc4b66e69d
 	google.golang.org/genproto v0.0.0-20181202183823-bd91e49a0898 // indirect
 	google.golang.org/grpc v1.17.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )